@@ -0,0 +1,62 @@
+package resterr
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const allocBudgetFile = "testdata/alloc_budget.json"
+
+var updateAllocBudget = flag.Bool("update-alloc-budget", false, "rewrite "+allocBudgetFile+" with the measured allocations")
+
+// TestAllocBudget fails when a path of BenchmarkHandle allocates more per call than its budget
+// in testdata/alloc_budget.json, so that allocation regressions fail go test rather than
+// waiting for someone to compare benchmarks. Paths allocating less should lower their budget
+// with -update-alloc-budget. It does not run in parallel, as other tests would allocate
+// while the allocations are counted.
+func TestAllocBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+
+	handler, paths := hotPaths(t)
+
+	got := make(map[string]float64, len(paths))
+	for _, path := range paths {
+		w := discardWriter{header: http.Header{}}
+		got[path.name] = testing.AllocsPerRun(100, func() {
+			handler.Handle(context.TODO(), &w, path.givenErr)
+		})
+	}
+
+	if *updateAllocBudget {
+		b, err := json.MarshalIndent(got, "", "\t")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(allocBudgetFile, append(b, '\n'), 0o644))
+		return
+	}
+
+	b, err := os.ReadFile(allocBudgetFile)
+	require.NoError(t, err)
+
+	var budget map[string]float64
+	require.NoError(t, json.Unmarshal(b, &budget))
+
+	for _, path := range paths {
+		want, ok := budget[path.name]
+		if !ok {
+			t.Errorf("%s: no allocation budget, run go test -run TestAllocBudget -update-alloc-budget", path.name)
+			continue
+		}
+
+		if got[path.name] > want {
+			t.Errorf("%s: %v allocs/op, budget is %v", path.name, got[path.name], want)
+		}
+	}
+}
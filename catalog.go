@@ -0,0 +1,277 @@
+package resterr
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Register maps err to e at runtime, replacing any existing mapping of err.
+// The REST error is validated and prepared like the ones given to NewHandler.
+// Errors matching several mappings resolve to the one registered first, after the ones
+// given to NewHandler, and replacing a mapping keeps its place.
+// A nil err cannot be mapped and returns an error.
+// It is safe to call concurrently with the handling of errors.
+func (h *Handler) Register(err error, e RESTErr) error {
+	if err == nil {
+		return errors.New("could not map nil error")
+	}
+
+	prepared, prepErr := h.prepare(e)
+	if prepErr != nil {
+		return prepErr
+	}
+	h.store(err, prepared)
+	return nil
+}
+
+// Unregister removes the mapping of err, if any, so that it is handled as unmapped.
+// It is safe to call concurrently with the handling of errors.
+func (h *Handler) Unregister(err error) {
+	h.remove(err)
+}
+
+// Reset removes every mapping from the error map. Domain error maps are left untouched.
+// It is safe to call concurrently with the handling of errors.
+func (h *Handler) Reset() {
+	h.rangeMappings(func(k any, _ RESTErr) bool {
+		h.remove(k)
+		return true
+	})
+}
+
+// store maps k to re in the error map. New keys are matched after the existing ones,
+// so that the first mapping registered wins when an error matches several of them.
+func (h *Handler) store(k any, re RESTErr) {
+	h.orderMu.Lock()
+	defer h.orderMu.Unlock()
+
+	h.errorMap.Store(k, re)
+
+	var order []any
+	if p := h.order.Load(); p != nil {
+		order = *p
+	}
+
+	if !slices.Contains(order, k) {
+		order = append(slices.Clip(order), k)
+		h.order.Store(&order)
+	}
+}
+
+// remove removes the mapping of k from the error map.
+func (h *Handler) remove(k any) {
+	h.orderMu.Lock()
+	defer h.orderMu.Unlock()
+
+	h.errorMap.Delete(k)
+
+	if p := h.order.Load(); p != nil {
+		order := slices.DeleteFunc(slices.Clone(*p), func(o any) bool { return o == k })
+		h.order.Store(&order)
+	}
+}
+
+// rangeMappings calls fn with the mappings of the error map in the order they were registered,
+// until fn returns false. It is safe to call concurrently with changes to the error map.
+func (h *Handler) rangeMappings(fn func(k any, re RESTErr) bool) {
+	p := h.order.Load()
+	if p == nil {
+		return
+	}
+
+	for _, k := range *p {
+		v, ok := h.errorMap.Load(k)
+		if !ok {
+			continue
+		}
+
+		re, ok := v.(RESTErr)
+		if !ok {
+			continue
+		}
+
+		if !fn(k, re) {
+			return
+		}
+	}
+}
+
+// Merge copies the mappings of other's error map into this handler, for composing catalogs
+// built independently. The imported REST errors are prepared like with Register, so they
+// are validated and serialized with the configuration of this handler. Mappings of errors
+// already mapped by this handler are conflicts. When there are conflicts or imported errors
+// fail validation, nothing is imported and the returned error joins all of them.
+func (h *Handler) Merge(other *Handler) error {
+	var (
+		errs     []error
+		imported = make(map[any]RESTErr)
+	)
+
+	state := other.Snapshot()
+	for _, k := range state.order {
+		re := state.mappings[k]
+		if _, ok := h.errorMap.Load(k); ok {
+			errs = append(errs, fmt.Errorf("conflicting mapping for error '%v'", k))
+			continue
+		}
+
+		re.json, re.localized, re.prepared = nil, nil, false
+
+		prepared, err := h.prepare(re)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		imported[k] = prepared
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not merge handler: %w", errors.Join(errs...))
+	}
+
+	for _, k := range state.order {
+		if re, ok := imported[k]; ok {
+			h.store(k, re)
+		}
+	}
+	return nil
+}
+
+// catalogLine is a line of the catalog written by DumpCatalog.
+type catalogLine struct {
+	Domain string          `json:"domain,omitempty"`
+	Key    string          `json:"key"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// DumpCatalog writes one JSON line per mapping of the handler, including the ones of domain
+// error maps, holding the message of the mapped error as key, and the status code and body of
+// the response it results in: {"key":"...","status":404,"body":{...}}. The domain of the
+// mappings of routes is the route, prefixed with "route", such as "route GET /users/*",
+// and the one of variants is their tag, prefixed with "variant", such as "variant rich".
+// Lines are sorted by domain, key, status and body, so that the output is deterministic
+// and can be committed as a golden file to catch unintended changes to the catalog.
+func (h *Handler) DumpCatalog(w io.Writer) error {
+	var lines []catalogLine
+
+	add := func(domain string, k any, re RESTErr) error {
+		status, body, err := h.response(re)
+		if err != nil {
+			return fmt.Errorf("could not marshal REST error for '%v': %w", k, err)
+		}
+
+		key := fmt.Sprint(k)
+		if keyErr, ok := k.(error); ok {
+			key = keyErr.Error()
+		}
+		lines = append(lines, catalogLine{Domain: domain, Key: key, Status: status, Body: body})
+		return nil
+	}
+
+	for k, re := range h.Snapshot().mappings {
+		if err := add("", k, re); err != nil {
+			return err
+		}
+	}
+
+	for domain, mappings := range h.domainMappings {
+		for _, m := range mappings {
+			if err := add(domain, m.err, m.restErr); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, route := range h.routes {
+		for _, m := range h.routeMappings[i] {
+			if err := add("route "+route.name(), m.err, m.restErr); err != nil {
+				return err
+			}
+		}
+	}
+
+	for tag, mappings := range h.variantMappings {
+		for _, m := range mappings {
+			if err := add("variant "+tag, m.err, m.restErr); err != nil {
+				return err
+			}
+		}
+	}
+
+	slices.SortFunc(lines, func(a, b catalogLine) int {
+		return cmp.Or(
+			cmp.Compare(a.Domain, b.Domain),
+			cmp.Compare(a.Key, b.Key),
+			cmp.Compare(a.Status, b.Status),
+			bytes.Compare(a.Body, b.Body),
+		)
+	})
+
+	enc := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("could not write catalog line for '%s': %w", line.Key, err)
+		}
+	}
+	return nil
+}
+
+// HandlerState is a copy of the mappings of a handler's error map, taken by Snapshot.
+type HandlerState struct {
+	mappings map[any]RESTErr
+	order    []any
+}
+
+// Snapshot returns a deep copy of the mappings of the error map, so that tests changing
+// the mappings of a shared handler can put them back with Restore.
+func (h *Handler) Snapshot() HandlerState {
+	state := HandlerState{mappings: make(map[any]RESTErr)}
+	h.rangeMappings(func(k any, re RESTErr) bool {
+		state.mappings[k] = cloneRESTErr(re)
+		state.order = append(state.order, k)
+		return true
+	})
+	return state
+}
+
+// Restore replaces the mappings of the error map with the ones of state.
+// The state is copied, so that it can be restored more than once.
+func (h *Handler) Restore(state HandlerState) {
+	h.Reset()
+	for _, k := range state.order {
+		h.store(k, cloneRESTErr(state.mappings[k]))
+	}
+}
+
+// cloneRESTErr returns a deep copy of e.
+func cloneRESTErr(e RESTErr) RESTErr {
+	e.Details = slices.Clone(e.Details)
+	e.Headers = e.Headers.Clone()
+	e.LogAttrs = slices.Clone(e.LogAttrs)
+	e.Translations = maps.Clone(e.Translations)
+	e.MessagesByEnv = maps.Clone(e.MessagesByEnv)
+	e.Extension = maps.Clone(e.Extension)
+	e.json = slices.Clone(e.json)
+
+	if e.localized != nil {
+		localized := make(map[string][]byte, len(e.localized))
+		for locale, b := range e.localized {
+			localized[locale] = slices.Clone(b)
+		}
+		e.localized = localized
+	}
+
+	if e.debug != nil {
+		debug := *e.debug
+		debug.Causes = slices.Clone(debug.Causes)
+		e.debug = &debug
+	}
+	return e
+}
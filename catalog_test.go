@@ -0,0 +1,320 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	t.Run("registers mapping", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Register(errFoo, RESTErr{StatusCode: http.StatusNotFound, Message: "not found"}))
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, http.StatusNotFound, writer.Code)
+		assert.JSONEq(t, `{"status-code":404,"message":"not found"}`, writer.Body.String())
+	})
+
+	t.Run("validates mapping", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, nil, WithValidationFn(func(e RESTErr) error {
+			return errors.New("invalid")
+		}))
+		require.NoError(t, err)
+
+		require.Error(t, handler.Register(errFoo, RESTErr{StatusCode: http.StatusNotFound, Message: "not found"}))
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, http.StatusInternalServerError, writer.Code)
+	})
+
+	t.Run("rejects nil error", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, nil)
+		require.NoError(t, err)
+
+		require.Error(t, handler.Register(nil, RESTErr{StatusCode: http.StatusNotFound, Message: "not found"}))
+
+		var mappings int
+		handler.rangeMappings(func(_ any, _ RESTErr) bool {
+			mappings++
+			return true
+		})
+		assert.Zero(t, mappings)
+	})
+}
+
+func TestUnregister(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: "not found"},
+	})
+	require.NoError(t, err)
+
+	handler.Unregister(errFoo)
+
+	writer := httptest.NewRecorder()
+	handler.Handle(context.TODO(), writer, errFoo)
+
+	assert.Equal(t, http.StatusInternalServerError, writer.Code)
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: "not found"},
+	}, WithStandardErrors())
+	require.NoError(t, err)
+
+	handler.Reset()
+
+	assert.Equal(t, []int{http.StatusInternalServerError}, handler.StatusCodes())
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+	errQux := errors.New("qux err")
+
+	t.Run("imports mappings", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{
+			errFoo: {StatusCode: http.StatusNotFound, Message: "not found"},
+		})
+		require.NoError(t, err)
+
+		other, err := NewHandler(logger, map[error]RESTErr{
+			errBar: {StatusCode: http.StatusConflict, Message: "conflict"},
+		}, WithMinimalFormat(""))
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Merge(other))
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errBar)
+
+		// Imported mappings are serialized with the format of the handler they are merged into.
+		assert.Equal(t, http.StatusConflict, writer.Code)
+		assert.JSONEq(t, `{"status-code":409,"message":"conflict"}`, writer.Body.String())
+
+		writer = httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, http.StatusNotFound, writer.Code)
+	})
+
+	t.Run("reports conflicts", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{
+			errFoo: {StatusCode: http.StatusNotFound, Message: "not found"},
+			errBar: {StatusCode: http.StatusConflict, Message: "conflict"},
+		})
+		require.NoError(t, err)
+
+		other, err := NewHandler(logger, map[error]RESTErr{
+			errFoo: {StatusCode: http.StatusGone, Message: "gone"},
+			errBar: {StatusCode: http.StatusGone, Message: "gone"},
+			errQux: {StatusCode: http.StatusGone, Message: "gone"},
+		})
+		require.NoError(t, err)
+
+		err = handler.Merge(other)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "foo err")
+		assert.Contains(t, err.Error(), "bar err")
+		assert.NotContains(t, err.Error(), "qux err")
+
+		// Nothing is imported when there are conflicts.
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errQux)
+
+		assert.Equal(t, http.StatusInternalServerError, writer.Code)
+
+		writer = httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, http.StatusNotFound, writer.Code)
+	})
+
+	t.Run("validates imported mappings", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, nil, WithValidationFn(func(e RESTErr) error {
+			if e.StatusCode < http.StatusBadRequest {
+				return errors.New("not an error status")
+			}
+			return nil
+		}))
+		require.NoError(t, err)
+
+		other, err := NewHandler(logger, map[error]RESTErr{
+			errFoo: {StatusCode: http.StatusOK, Message: "ok"},
+		})
+		require.NoError(t, err)
+
+		require.Error(t, handler.Merge(other))
+	})
+}
+
+func TestDumpCatalog(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+	errQux := errors.New("qux err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: "not found"},
+		errBar: {StatusCode: http.StatusConflict, Message: "conflict", Details: []Detail{{Field: "email", Message: "taken"}}},
+		errQux: {StatusCode: http.StatusGone, Message: "gone"},
+	},
+		WithDomainRouter(func(error) string { return "billing" }, map[string]map[error]RESTErr{
+			"billing": {errFoo: {StatusCode: http.StatusPaymentRequired, Message: "payment required"}},
+		}),
+		WithRoutes(Route{
+			Path:     "/orders/*",
+			ErrorMap: map[error]RESTErr{errFoo: {StatusCode: http.StatusGone, Message: "order deleted"}},
+		}),
+		WithVariants(func(*http.Request) string { return "" }, map[error]map[string]RESTErr{
+			errFoo: {"rich": {StatusCode: http.StatusUnprocessableEntity, Message: "invalid form"}},
+		}),
+	)
+	require.NoError(t, err)
+
+	expected := `{"key":"bar err","status":409,"body":{"status-code":409,"message":"conflict","details":[{"field":"email","message":"taken"}]}}
+{"key":"foo err","status":404,"body":{"status-code":404,"message":"not found"}}
+{"key":"qux err","status":410,"body":{"status-code":410,"message":"gone"}}
+{"domain":"billing","key":"foo err","status":402,"body":{"status-code":402,"message":"payment required"}}
+{"domain":"route * /orders/*","key":"foo err","status":410,"body":{"status-code":410,"message":"order deleted"}}
+{"domain":"variant rich","key":"foo err","status":422,"body":{"status-code":422,"message":"invalid form"}}
+`
+
+	// Map iteration order is random, so repeated dumps must be identical.
+	for i := 0; i < 10; i++ {
+		var buf strings.Builder
+		require.NoError(t, handler.DumpCatalog(&buf))
+
+		assert.Equal(t, expected, buf.String())
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+			Headers:    http.Header{"X-Foo": []string{"foo"}},
+		},
+	})
+	require.NoError(t, err)
+
+	state := handler.Snapshot()
+
+	testCases := []struct {
+		name   string
+		mutate func(t *testing.T)
+	}{
+		{
+			name: "registered mapping",
+			mutate: func(t *testing.T) {
+				require.NoError(t, handler.Register(errBar, RESTErr{StatusCode: http.StatusConflict, Message: "conflict"}))
+			},
+		},
+		{
+			name: "unregistered mapping",
+			mutate: func(t *testing.T) {
+				handler.Unregister(errFoo)
+			},
+		},
+		{
+			name: "reset mappings",
+			mutate: func(t *testing.T) {
+				handler.Reset()
+			},
+		},
+	}
+
+	// Cases share the handler, so they run sequentially and each restores the snapshot.
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mutate(t)
+			handler.Restore(state)
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, errFoo)
+
+			assert.Equal(t, http.StatusNotFound, writer.Code)
+			assert.Equal(t, "foo", writer.Header().Get("X-Foo"))
+
+			writer = httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, errBar)
+
+			assert.Equal(t, http.StatusInternalServerError, writer.Code)
+		})
+	}
+}
+
+func TestCloneRESTErr(t *testing.T) {
+	t.Parallel()
+
+	e := RESTErr{
+		StatusCode:   http.StatusNotFound,
+		Message:      "not found",
+		Details:      []Detail{{Field: "id", Message: "unknown"}},
+		Headers:      http.Header{"X-Foo": []string{"foo"}},
+		Translations: map[string]string{"pt-BR": "não encontrado"},
+		json:         []byte(`{}`),
+		localized:    map[string][]byte{"pt-BR": []byte(`{}`)},
+	}
+
+	clone := cloneRESTErr(e)
+	require.Equal(t, e, clone)
+
+	clone.Details[0].Message = "mutated"
+	clone.Headers.Set("X-Foo", "mutated")
+	clone.Translations["pt-BR"] = "mutated"
+	clone.json[0] = '['
+	clone.localized["pt-BR"][0] = '['
+
+	assert.Equal(t, "unknown", e.Details[0].Message)
+	assert.Equal(t, "foo", e.Headers.Get("X-Foo"))
+	assert.Equal(t, "não encontrado", e.Translations["pt-BR"])
+	assert.Equal(t, `{}`, string(e.json))
+	assert.Equal(t, `{}`, string(e.localized["pt-BR"]))
+}
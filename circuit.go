@@ -0,0 +1,85 @@
+package resterr
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is a sentinel error for calls rejected by an open circuit breaker.
+// Breaker implementations can wrap it, or return their own errors recognized by IsCircuitOpen.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitOpenMessages are the messages of the open-state errors of popular circuit breaker
+// libraries, which are matched by message to keep them out of the dependencies.
+var circuitOpenMessages = []string{
+	"circuit breaker is open", // github.com/sony/gobreaker, github.com/eapache/go-resiliency
+	"hystrix: circuit open",   // github.com/afex/hystrix-go
+	"circuit breaker open",    // github.com/failsafe-go/failsafe-go
+}
+
+// CircuitOpenErr returns a 503 Service Unavailable REST error for calls rejected by an open
+// circuit breaker. When retryAfter is positive, it is sent in the Retry-After header,
+// rounded up to the second.
+func CircuitOpenErr(retryAfter time.Duration) RESTErr {
+	e := RESTErr{
+		StatusCode: http.StatusServiceUnavailable,
+		Message:    "service temporarily unavailable",
+	}
+
+	if retryAfter > 0 {
+		e.Headers = http.Header{
+			"Retry-After": []string{retryAfterSeconds(retryAfter)},
+		}
+	}
+	return e
+}
+
+// retryAfterSeconds formats d as a Retry-After header value, rounded up to the second.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// IsCircuitOpen reports whether err, or any error it wraps, is ErrCircuitOpen
+// or the open-state error of a popular circuit breaker library.
+func IsCircuitOpen(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+
+	queue := []error{err}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+
+		if e == nil {
+			continue
+		}
+
+		if slices.Contains(circuitOpenMessages, e.Error()) {
+			return true
+		}
+
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			queue = append(queue, u.Unwrap())
+		case interface{ Unwrap() []error }:
+			queue = append(queue, u.Unwrap()...)
+		}
+	}
+	return false
+}
+
+// CircuitOpenMapper returns a fallback mapper, for use with WithFallbackMapper,
+// that maps errors recognized by IsCircuitOpen to CircuitOpenErr(retryAfter).
+func CircuitOpenMapper(retryAfter time.Duration) func(err error) (RESTErr, bool) {
+	return func(err error) (RESTErr, bool) {
+		if !IsCircuitOpen(err) {
+			return RESTErr{}, false
+		}
+		return CircuitOpenErr(retryAfter), true
+	}
+}
@@ -0,0 +1,102 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitOpenErr(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		givenRetryAfter    time.Duration
+		expectedRetryAfter string
+	}{
+		{
+			name: "without retry after",
+		},
+		{
+			name:               "with whole seconds",
+			givenRetryAfter:    30 * time.Second,
+			expectedRetryAfter: "30",
+		},
+		{
+			name:               "with fraction of seconds",
+			givenRetryAfter:    1500 * time.Millisecond,
+			expectedRetryAfter: "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			observed := CircuitOpenErr(tc.givenRetryAfter)
+
+			assert.Equal(t, http.StatusServiceUnavailable, observed.StatusCode)
+			assert.Equal(t, tc.expectedRetryAfter, observed.Headers.Get("Retry-After"))
+		})
+	}
+}
+
+func TestIsCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		givenErr error
+		expected bool
+	}{
+		{
+			name:     "sentinel",
+			givenErr: fmt.Errorf("call payments: %w", ErrCircuitOpen),
+			expected: true,
+		},
+		{
+			name:     "library error",
+			givenErr: fmt.Errorf("call payments: %w", errors.New("hystrix: circuit open")),
+			expected: true,
+		},
+		{
+			name:     "joined library error",
+			givenErr: errors.Join(errors.New("foo err"), errors.New("circuit breaker is open")),
+			expected: true,
+		},
+		{
+			name:     "other error",
+			givenErr: errors.New("call payments: circuit breaker is open for maintenance"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, IsCircuitOpen(tc.givenErr))
+		})
+	}
+}
+
+func TestHandleWithCircuitOpenMapper(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithFallbackMapper(CircuitOpenMapper(10*time.Second)))
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, fmt.Errorf("call payments: %w", ErrCircuitOpen))
+
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+	assert.Equal(t, "10", writer.Header().Get("Retry-After"))
+}
@@ -0,0 +1,171 @@
+package resterr
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// HandlerConfig describes the effective configuration of a handler, such as for an admin endpoint
+// or a startup log line reporting why a service formats errors differently than another.
+type HandlerConfig struct {
+	Format             string          `json:"format"`
+	ContentType        string          `json:"content-type"`
+	ProblemParamsKey   string          `json:"problem-params-key,omitempty"`
+	EmptyDetails       string          `json:"empty-details"`
+	EmptyMap           string          `json:"empty-map"`
+	Environment        string          `json:"environment,omitempty"`
+	ErrorKey           string          `json:"error-key"`
+	RESTErrorKey       string          `json:"rest-error-key"`
+	Mappings           int             `json:"mappings"`
+	AutoRegistered     int             `json:"auto-registered,omitempty"`
+	Domains            []string        `json:"domains,omitempty"`
+	Routes             int             `json:"routes,omitempty"`
+	Variants           []string        `json:"variants,omitempty"`
+	Deprecations       int             `json:"deprecations,omitempty"`
+	StandardErrors     bool            `json:"standard-errors"`
+	ExtendedStatuses   bool            `json:"extended-statuses"`
+	NoStore            bool            `json:"no-store"`
+	StatusAsString     bool            `json:"status-as-string"`
+	DefaultSeverity    bool            `json:"default-severity"`
+	AutoFlush          bool            `json:"auto-flush"`
+	ErrorIDs           bool            `json:"error-ids"`
+	StatusHeader       string          `json:"status-header,omitempty"`
+	RequestIDHeader    string          `json:"request-id-header,omitempty"`
+	MaxHeaders         int             `json:"max-headers,omitempty"`
+	MaxMessageLength   int             `json:"max-message-length,omitempty"`
+	SummaryMaxBytes    int             `json:"summary-max-bytes,omitempty"`
+	HTMLErrorPages     []int           `json:"html-error-pages,omitempty"`
+	ReportThreshold    int             `json:"report-threshold,omitempty"`
+	ErrorLogThreshold  int             `json:"error-log-threshold"`
+	Sunset             string          `json:"sunset,omitempty"`
+	RetryAfter         map[int]string  `json:"retry-after,omitempty"`
+	FallbackError      *RESTErr        `json:"fallback-error,omitempty"`
+	ClientErrorDefault *RESTErr        `json:"client-error-default,omitempty"`
+	ServerErrorDefault *RESTErr        `json:"server-error-default,omitempty"`
+	StatusFallbacks    map[int]RESTErr `json:"status-fallbacks,omitempty"`
+	Hooks              []string        `json:"hooks,omitempty"`
+}
+
+// Config returns the effective configuration of h. Format is "json", "problem-details" or
+// "custom" for custom marshal functions, such as the one of WithMinimalFormat. Mappings is the
+// number of errors in the error map, including the ones registered after the handler was created
+// and the AutoRegistered ones. EmptyMap is "allow", "warn" or "require", the policy of
+// WithWarnOnEmptyMap and WithRequireNonEmptyMap, and Variants lists, sorted, the tags of WithVariants.
+// HTMLErrorPages lists the status codes with an HTML error page, 0 standing for every server error.
+// ReportThreshold is only set with an error reporter, and Sunset, an HTTP-date, with WithDeprecation.
+// Hooks lists, sorted, the options set with functions or context keys, such as "WithValidationFn"
+// or "WithLocaleFromContext", whose behavior cannot be described otherwise.
+// RetryAfter holds the Retry-After values of WithDefaultRetryAfter by status code, and the REST error
+// fields the ones of WithFallbackRESTErr, WithClientErrorDefault, WithServerErrorDefault and
+// WithInternalErrorForStatus, if set.
+func (h *Handler) Config() HandlerConfig {
+	c := HandlerConfig{
+		Format:            h.format().String(),
+		ContentType:       h.contentType(RESTErr{}),
+		ProblemParamsKey:  h.problemParamsKey,
+		EmptyDetails:      h.emptyDetails.String(),
+		EmptyMap:          h.emptyMap.String(),
+		Environment:       h.env,
+		ErrorKey:          h.errKey,
+		RESTErrorKey:      h.restErrKey,
+		AutoRegistered:    len(h.autoErrors),
+		Routes:            len(h.routes),
+		Deprecations:      len(h.deprecations),
+		StandardErrors:    h.stdErrors,
+		ExtendedStatuses:  h.extendedStatuses,
+		NoStore:           h.noStore,
+		StatusAsString:    h.statusAsString,
+		DefaultSeverity:   h.defaultSeverity,
+		AutoFlush:         h.autoFlush,
+		ErrorIDs:          h.errorIDFn != nil,
+		StatusHeader:      h.statusHeader,
+		RequestIDHeader:   h.requestIDHeader,
+		MaxHeaders:        h.maxHeaders,
+		MaxMessageLength:  h.maxMessageLen,
+		SummaryMaxBytes:   h.summaryMaxBytes,
+		ErrorLogThreshold: h.errLogThreshold,
+	}
+
+	h.rangeMappings(func(_ any, _ RESTErr) bool {
+		c.Mappings++
+		return true
+	})
+
+	for statusCode := range h.htmlPages {
+		c.HTMLErrorPages = append(c.HTMLErrorPages, statusCode)
+	}
+	slices.Sort(c.HTMLErrorPages)
+
+	if !h.sunset.IsZero() {
+		c.Sunset = h.sunset.UTC().Format(http.TimeFormat)
+	}
+
+	for domain := range h.domains {
+		c.Domains = append(c.Domains, domain)
+	}
+	slices.Sort(c.Domains)
+
+	for tag := range h.variants {
+		c.Variants = append(c.Variants, tag)
+	}
+	slices.Sort(c.Variants)
+
+	if h.reportFn != nil {
+		c.ReportThreshold = h.reportThreshold
+	}
+
+	c.RetryAfter = maps.Clone(h.retryAfter)
+	c.StatusFallbacks = maps.Clone(h.statusFallbacks)
+
+	if !h.fallbackErr.Equal(RESTErr{}) {
+		fallbackErr := h.fallbackErr
+		c.FallbackError = &fallbackErr
+	}
+
+	if h.clientDefault != nil {
+		clientDefault := *h.clientDefault
+		c.ClientErrorDefault = &clientDefault
+	}
+
+	if h.serverDefault != nil {
+		serverDefault := *h.serverDefault
+		c.ServerErrorDefault = &serverDefault
+	}
+
+	hooks := []struct {
+		name string
+		set  bool
+	}{
+		{"WithValidationFn", h.validationFn != nil},
+		{"WithUnwrapper", h.unwrapFn != nil},
+		{"WithStatusCodeRewriter", h.statusRewriteFn != nil},
+		{"WithOnHandle", h.onHandleFn != nil},
+		{"WithErrorReporter", h.reportFn != nil},
+		{"WithMetricsHook", len(h.metricsHooks) > 0},
+		{"WithResponseValidator", h.responseValidFn != nil},
+		{"WithRequestIDGenerator", h.requestIDFn != nil},
+		{"WithErrorStringNormalizer", h.normalizeFn != nil},
+		{"WithDomainRouter", h.domainFn != nil},
+		{"WithFallbackMapper", len(h.fallbackMappers) > 0},
+		{"WithErrorIDWhen", h.errorIDWhenFn != nil},
+		{"WithCodeMapper", h.codeMapperFn != nil},
+		{"WithPanicMapper", h.panicMapperFn != nil},
+		{"WithTraceContextHeader", h.traceparentFn != nil},
+		{"WithLogRedactor", len(h.logRedactions) > 0},
+		{"WithResponseDelay", h.delayFn != nil},
+		{"WithWriteFunc", h.writeFn != nil},
+		{"WithVariants", h.variantFn != nil},
+		{"WithLocaleFromContext", h.localeKey != nil},
+		{"WithDebugFromContext", h.debugKey != nil},
+		{"WithFormatFromContext", h.formatKey != nil},
+	}
+	for _, hook := range hooks {
+		if hook.set {
+			c.Hooks = append(c.Hooks, hook.name)
+		}
+	}
+	slices.Sort(c.Hooks)
+
+	return c
+}
@@ -0,0 +1,144 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		givenOpts []Option
+		expected  HandlerConfig
+	}{
+		{
+			name: "defaults",
+			expected: HandlerConfig{
+				Format:            "json",
+				ContentType:       "application/json",
+				EmptyDetails:      "omit",
+				EmptyMap:          "allow",
+				ErrorKey:          "error",
+				RESTErrorKey:      "rest-error",
+				Mappings:          1,
+				ErrorLogThreshold: http.StatusInternalServerError,
+			},
+		},
+		{
+			name: "with options",
+			givenOpts: []Option{
+				WithProblemDetails(),
+				WithEmptyDetailsBehavior(EmptyDetailsArray),
+				WithEnvironment("staging"),
+				WithLogKeys("err", "rest_err"),
+				WithStandardErrors(),
+				WithNoStore(),
+				WithErrorID(nil),
+				WithMaxHeaders(10),
+				WithErrorReporter(func(_ context.Context, _ error, _ RESTErr) {}),
+				WithValidationFn(func(_ RESTErr) error { return nil }),
+				WithLocaleFromContext("locale"),
+				WithDomainRouter(func(_ error) string { return "" }, map[string]map[error]RESTErr{
+					"billing": {},
+					"auth":    {},
+				}),
+			},
+			expected: HandlerConfig{
+				Format:            "problem-details",
+				ContentType:       "application/problem+json",
+				EmptyDetails:      "array",
+				EmptyMap:          "allow",
+				Environment:       "staging",
+				ErrorKey:          "err",
+				RESTErrorKey:      "rest_err",
+				Mappings:          3,
+				Domains:           []string{"auth", "billing"},
+				StandardErrors:    true,
+				NoStore:           true,
+				ErrorIDs:          true,
+				MaxHeaders:        10,
+				ReportThreshold:   http.StatusInternalServerError,
+				ErrorLogThreshold: http.StatusInternalServerError,
+				Hooks:             []string{"WithDomainRouter", "WithErrorReporter", "WithLocaleFromContext", "WithValidationFn"},
+			},
+		},
+		{
+			name: "with fallbacks",
+			givenOpts: []Option{
+				WithRequireNonEmptyMap(),
+				AutoRegister(errors.New("user not found")),
+				WithDefaultRetryAfter(http.StatusServiceUnavailable, 30*time.Second),
+				WithFallbackRESTErr(RESTErr{Message: "request failed"}),
+				WithClientErrorDefault(RESTErr{StatusCode: http.StatusBadRequest, Message: "bad request"}),
+				WithServerErrorDefault(RESTErr{Message: "server failed"}),
+				WithInternalErrorForStatus(map[int]RESTErr{http.StatusBadGateway: {Message: "bad gateway"}}),
+				WithVariants(func(_ *http.Request) string { return "" }, map[error]map[string]RESTErr{
+					errFoo: {
+						"rich": {StatusCode: http.StatusNotFound, Message: "user not found"},
+						"lite": {StatusCode: http.StatusNotFound, Message: "not found"},
+					},
+				}),
+			},
+			expected: HandlerConfig{
+				Format:             "json",
+				ContentType:        "application/json",
+				EmptyDetails:       "omit",
+				EmptyMap:           "require",
+				ErrorKey:           "error",
+				RESTErrorKey:       "rest-error",
+				Mappings:           2,
+				AutoRegistered:     1,
+				Variants:           []string{"lite", "rich"},
+				ErrorLogThreshold:  http.StatusInternalServerError,
+				RetryAfter:         map[int]string{http.StatusServiceUnavailable: "30"},
+				FallbackError:      &RESTErr{Message: "request failed"},
+				ClientErrorDefault: &RESTErr{StatusCode: http.StatusBadRequest, Message: "bad request"},
+				ServerErrorDefault: &RESTErr{Message: "server failed"},
+				StatusFallbacks:    map[int]RESTErr{http.StatusBadGateway: {Message: "bad gateway"}},
+				Hooks:              []string{"WithVariants"},
+			},
+		},
+		{
+			name:      "custom format",
+			givenOpts: []Option{WithMinimalFormat("")},
+			expected: HandlerConfig{
+				Format:            "custom",
+				ContentType:       "application/json",
+				EmptyDetails:      "omit",
+				EmptyMap:          "allow",
+				ErrorKey:          "error",
+				RESTErrorKey:      "rest-error",
+				Mappings:          1,
+				ErrorLogThreshold: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, handler.Config())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package dberr maps the errors of database drivers to REST errors, so that common database
+// failures such as unique constraint violations do not result in internal server errors.
+//
+// It has no dependency on the drivers: PostgreSQL errors are recognized by their SQLSTATE
+// code, as returned by the SQLState method of pgconn.PgError and pq.Error, and MySQL errors
+// by the error number of mysql.MySQLError.
+package dberr
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/alesr/resterr"
+)
+
+// SQLStates maps PostgreSQL SQLSTATE codes to REST errors.
+var SQLStates = map[string]resterr.RESTErr{
+	"23505": {StatusCode: http.StatusConflict, Message: "resource already exists"},
+	"23503": {StatusCode: http.StatusConflict, Message: "resource is referenced or references a missing resource"},
+	"23502": {StatusCode: http.StatusBadRequest, Message: "missing required value"},
+}
+
+// MySQLErrors maps MySQL error numbers to REST errors.
+var MySQLErrors = map[uint16]resterr.RESTErr{
+	1062: {StatusCode: http.StatusConflict, Message: "resource already exists"},
+	1451: {StatusCode: http.StatusConflict, Message: "resource is referenced or references a missing resource"},
+	1452: {StatusCode: http.StatusConflict, Message: "resource is referenced or references a missing resource"},
+	1048: {StatusCode: http.StatusBadRequest, Message: "missing required value"},
+}
+
+var notFoundErr = resterr.RESTErr{
+	StatusCode: http.StatusNotFound,
+	Message:    "resource not found",
+}
+
+// sqlStater is implemented by PostgreSQL driver errors.
+type sqlStater interface {
+	SQLState() string
+}
+
+// Mapper is a fallback mapper, for use with resterr.WithFallbackMapper, that maps
+// sql.ErrNoRows to 404 Not Found and driver errors found in SQLStates and MySQLErrors.
+func Mapper(err error) (resterr.RESTErr, bool) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundErr, true
+	}
+
+	var pgErr sqlStater
+	if errors.As(err, &pgErr) {
+		e, ok := SQLStates[pgErr.SQLState()]
+		return e, ok
+	}
+
+	if number, ok := mySQLErrorNumber(err); ok {
+		e, ok := MySQLErrors[number]
+		return e, ok
+	}
+	return resterr.RESTErr{}, false
+}
+
+// mySQLErrorNumber returns the error number of the first MySQL driver error in the tree of err.
+// The driver error is recognized by its type name and Number field.
+func mySQLErrorNumber(err error) (uint16, bool) {
+	queue := []error{err}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+
+		if e == nil {
+			continue
+		}
+
+		v := reflect.ValueOf(e)
+		if v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+
+		if v.Kind() == reflect.Struct && v.Type().Name() == "MySQLError" {
+			if f := v.FieldByName("Number"); f.IsValid() && f.Kind() == reflect.Uint16 {
+				return uint16(f.Uint()), true
+			}
+		}
+
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			queue = append(queue, u.Unwrap())
+		case interface{ Unwrap() []error }:
+			queue = append(queue, u.Unwrap()...)
+		}
+	}
+	return 0, false
+}
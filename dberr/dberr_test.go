@@ -0,0 +1,118 @@
+package dberr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alesr/resterr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+type PgError struct {
+	Code string
+}
+
+func (e *PgError) Error() string    { return "pg error " + e.Code }
+func (e *PgError) SQLState() string { return e.Code }
+
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+func TestMapper(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedFound      bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "no rows",
+			givenErr:           fmt.Errorf("find user: %w", sql.ErrNoRows),
+			expectedFound:      true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "postgres unique violation",
+			givenErr:           fmt.Errorf("insert user: %w", &PgError{Code: "23505"}),
+			expectedFound:      true,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "postgres foreign key violation",
+			givenErr:           &PgError{Code: "23503"},
+			expectedFound:      true,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "postgres not null violation",
+			givenErr:           &PgError{Code: "23502"},
+			expectedFound:      true,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:     "postgres unknown code",
+			givenErr: &PgError{Code: "57014"},
+		},
+		{
+			name:               "mysql duplicate entry",
+			givenErr:           fmt.Errorf("insert user: %w", &MySQLError{Number: 1062, Message: "Duplicate entry"}),
+			expectedFound:      true,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "mysql column cannot be null",
+			givenErr:           errors.Join(errors.New("insert user"), &MySQLError{Number: 1048}),
+			expectedFound:      true,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:     "mysql unknown number",
+			givenErr: &MySQLError{Number: 1205},
+		},
+		{
+			name:     "other error",
+			givenErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, found := Mapper(tc.givenErr)
+
+			require.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expectedStatusCode, e.StatusCode)
+		})
+	}
+}
+
+func TestMapperWithHandler(t *testing.T) {
+	t.Parallel()
+
+	handler, err := resterr.NewHandler(logger, nil, resterr.WithFallbackMapper(Mapper))
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, &PgError{Code: "23505"})
+
+	assert.Equal(t, http.StatusConflict, writer.Code)
+	assert.JSONEq(t, `{"status-code":409,"message":"resource already exists"}`, writer.Body.String())
+}
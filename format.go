@@ -0,0 +1,101 @@
+package resterr
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Format is a serialization format of REST errors, selected per request
+// by handlers configured with WithFormatFromContext.
+type Format int
+
+const (
+	// FormatJSON is the default JSON format, with the application/json content type.
+	FormatJSON Format = iota + 1
+	// FormatProblemDetails is the RFC 9457 format of WithProblemDetails,
+	// with the application/problem+json content type.
+	FormatProblemDetails
+)
+
+// String returns the name of f, "json" or "problem-details", and "custom" for the zero Format
+// of custom marshal functions.
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatProblemDetails:
+		return "problem-details"
+	default:
+		return "custom"
+	}
+}
+
+// WithFormatFromContext is an option to select the format of REST errors with the Format stored
+// in the context under key, such as by the routing middleware of a route group, so that a single
+// handler serves several formats. Requests without a format get the handler's format.
+// REST errors in another format than the handler's are marshaled on each write.
+func WithFormatFromContext(key any) Option {
+	return func(h *Handler) {
+		h.formatKey = key
+	}
+}
+
+// selectFormat sets the format of e to the one in ctx, if other than the handler's.
+func (h *Handler) selectFormat(ctx context.Context, e RESTErr) RESTErr {
+	if h.formatKey == nil {
+		return e
+	}
+
+	f, ok := ctx.Value(h.formatKey).(Format)
+	if !ok || f == h.format() {
+		return e
+	}
+
+	if f != FormatJSON && f != FormatProblemDetails {
+		h.logger.WarnContext(ctx, "Ignoring unknown format.", slog.Int("format", int(f)))
+		return e
+	}
+
+	e.format = f
+	e.json = nil
+	return e
+}
+
+// format returns the format the handler is configured with,
+// or zero for custom formats such as the one of WithMinimalFormat.
+func (h *Handler) format() Format {
+	switch {
+	case h.problemDetails:
+		return FormatProblemDetails
+	case h.marshalFn == nil:
+		return FormatJSON
+	default:
+		return 0
+	}
+}
+
+// bodyFormat returns the format e is serialized with: the default JSON format for errors with
+// debug information, the format selected by the request context, if any, or the handler's format.
+func (h *Handler) bodyFormat(e RESTErr) Format {
+	switch {
+	case e.debug != nil:
+		return FormatJSON
+	case e.format != 0:
+		return e.format
+	default:
+		return h.format()
+	}
+}
+
+// problemFormat reports whether e is serialized as a problem details document.
+func (h *Handler) problemFormat(e RESTErr) bool {
+	return h.bodyFormat(e) == FormatProblemDetails
+}
+
+// contentType returns the media type of the body of e.
+func (h *Handler) contentType(e RESTErr) string {
+	if h.problemFormat(e) {
+		return problemContentType
+	}
+	return "application/json"
+}
@@ -0,0 +1,182 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formatCtxKey struct{}
+
+func TestHandleWithFormatFromContext(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}
+
+	const (
+		jsonBody    = `{"status-code":404,"message":"not found"}`
+		problemBody = `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found"}`
+	)
+
+	testCases := []struct {
+		name                string
+		givenOpts           []Option
+		givenFormat         any
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "JSON handler without format",
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+		{
+			name:                "JSON handler with problem details format",
+			givenFormat:         FormatProblemDetails,
+			expectedContentType: "application/problem+json",
+			expectedBody:        problemBody,
+		},
+		{
+			name:                "JSON handler with JSON format",
+			givenFormat:         FormatJSON,
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+		{
+			name:                "problem details handler with JSON format",
+			givenOpts:           []Option{WithProblemDetails()},
+			givenFormat:         FormatJSON,
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+		{
+			name:                "problem details handler without format",
+			givenOpts:           []Option{WithProblemDetails()},
+			expectedContentType: "application/problem+json",
+			expectedBody:        problemBody,
+		},
+		{
+			name:                "minimal handler with JSON format",
+			givenOpts:           []Option{WithMinimalFormat("")},
+			givenFormat:         FormatJSON,
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+		{
+			name:                "unknown format",
+			givenFormat:         Format(42),
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+		{
+			name:                "invalid format",
+			givenFormat:         "problem",
+			expectedContentType: "application/json",
+			expectedBody:        jsonBody,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{WithFormatFromContext(formatCtxKey{})}, tc.givenOpts...)
+
+			handler, err := NewHandler(logger, errorMap, opts...)
+			require.NoError(t, err)
+
+			ctx := context.TODO()
+			if tc.givenFormat != nil {
+				ctx = context.WithValue(ctx, formatCtxKey{}, tc.givenFormat)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(ctx, writer, errFoo)
+
+			assert.Equal(t, http.StatusNotFound, writer.Code)
+			assert.Equal(t, tc.expectedContentType, writer.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithCombinedFormats(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}
+
+	testCases := []struct {
+		name                string
+		givenOpts           []Option
+		givenDebug          bool
+		expectedFormat      string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "minimal format after problem details",
+			givenOpts:           []Option{WithProblemDetails(), WithMinimalFormat("")},
+			expectedFormat:      "custom",
+			expectedContentType: "application/json",
+			expectedBody:        `{"m":"not found"}`,
+		},
+		{
+			name:                "problem details after minimal format",
+			givenOpts:           []Option{WithMinimalFormat(""), WithProblemDetails()},
+			expectedFormat:      "problem-details",
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found"}`,
+		},
+		{
+			name:                "problem details in debug mode",
+			givenOpts:           []Option{WithProblemDetails(), WithDebugFromContext(debugCtxKey{})},
+			givenDebug:          true,
+			expectedFormat:      "problem-details",
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"not found","debug":{"error":"foo err"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedFormat, handler.Config().Format)
+
+			ctx := context.TODO()
+			if tc.givenDebug {
+				ctx = context.WithValue(ctx, debugCtxKey{}, true)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(ctx, writer, errFoo)
+
+			assert.Equal(t, tc.expectedContentType, writer.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package grpcerr maps gRPC statuses to REST errors, for gRPC-HTTP gateways whose clients
+// migrate from gRPC to REST. The REST errors carry the code of the original status in their
+// grpc_code extension member, so that clients can correlate errors.
+//
+// It lives in its own module so that the core package does not depend on gRPC.
+// FromGRPCStatus is a fallback mapper:
+//
+//	handler, err := resterr.NewHandler(logger, errMap, resterr.WithFallbackMapper(grpcerr.FromGRPCStatus))
+package grpcerr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alesr/resterr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusCodes maps gRPC codes to HTTP status codes, following the gRPC-HTTP gateway conventions.
+var StatusCodes = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// grpcCodeMember is the extension member of REST errors holding the code of the gRPC status.
+const grpcCodeMember = "grpc_code"
+
+// statusTexts are the texts of the status codes of StatusCodes that are not standard,
+// for which http.StatusText is empty.
+var statusTexts = map[int]string{
+	499: "Client Closed Request",
+}
+
+// grpcStatuser is implemented by errors carrying a gRPC status, such as the ones of the status package.
+type grpcStatuser interface {
+	GRPCStatus() *status.Status
+}
+
+// FromGRPCStatus returns the REST error for the gRPC status of err, with the HTTP status code
+// from StatusCodes and the gRPC code in the grpc_code extension member. The status message is kept
+// for client errors, while server errors get the status text so that internal details
+// are not leaked. It reports false for errors without a gRPC status, with the OK code
+// or with an unknown code.
+func FromGRPCStatus(err error) (resterr.RESTErr, bool) {
+	// status.FromError would prefix the message of wrapped statuses with the wrapping errors.
+	var se grpcStatuser
+	if !errors.As(err, &se) {
+		return resterr.RESTErr{}, false
+	}
+
+	st := se.GRPCStatus()
+	if st.Code() == codes.OK {
+		return resterr.RESTErr{}, false
+	}
+
+	statusCode, ok := StatusCodes[st.Code()]
+	if !ok {
+		return resterr.RESTErr{}, false
+	}
+
+	msg := st.Message()
+	if msg == "" || statusCode >= http.StatusInternalServerError {
+		msg = statusText(statusCode)
+	}
+
+	return resterr.RESTErr{
+		StatusCode: statusCode,
+		Message:    msg,
+		Extension:  map[string]any{grpcCodeMember: int(st.Code())},
+	}, true
+}
+
+// statusText returns the text of statusCode, including the ones of statusTexts.
+func statusText(statusCode int) string {
+	if text, ok := statusTexts[statusCode]; ok {
+		return text
+	}
+	return http.StatusText(statusCode)
+}
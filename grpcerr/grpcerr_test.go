@@ -0,0 +1,112 @@
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alesr/resterr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func TestFromGRPCStatus(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		given         error
+		expected      resterr.RESTErr
+		expectedFound bool
+	}{
+		{
+			name:  "client error",
+			given: status.Error(codes.NotFound, "user 42 not found"),
+			expected: resterr.RESTErr{
+				StatusCode: http.StatusNotFound,
+				Message:    "user 42 not found",
+				Extension:  map[string]any{"grpc_code": int(codes.NotFound)},
+			},
+			expectedFound: true,
+		},
+		{
+			name:  "server error hides the status message",
+			given: status.Error(codes.Internal, "nil pointer dereference"),
+			expected: resterr.RESTErr{
+				StatusCode: http.StatusInternalServerError,
+				Message:    "Internal Server Error",
+				Extension:  map[string]any{"grpc_code": int(codes.Internal)},
+			},
+			expectedFound: true,
+		},
+		{
+			name:  "wrapped status",
+			given: fmt.Errorf("could not get user: %w", status.Error(codes.Unauthenticated, "")),
+			expected: resterr.RESTErr{
+				StatusCode: http.StatusUnauthorized,
+				Message:    "Unauthorized",
+				Extension:  map[string]any{"grpc_code": int(codes.Unauthenticated)},
+			},
+			expectedFound: true,
+		},
+		{
+			name:  "client closed request",
+			given: status.Error(codes.Canceled, ""),
+			expected: resterr.RESTErr{
+				StatusCode: 499,
+				Message:    "Client Closed Request",
+				Extension:  map[string]any{"grpc_code": int(codes.Canceled)},
+			},
+			expectedFound: true,
+		},
+		{
+			name:  "OK status",
+			given: status.Error(codes.OK, ""),
+		},
+		{
+			name:  "not a status",
+			given: errors.New("foo err"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			observed, found := FromGRPCStatus(tc.given)
+
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expected, observed)
+		})
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	for c := codes.Canceled; c <= codes.Unauthenticated; c++ {
+		assert.Contains(t, StatusCodes, c, "code %s is not mapped", c)
+	}
+}
+
+func TestFromGRPCStatusAsFallbackMapper(t *testing.T) {
+	t.Parallel()
+
+	handler, err := resterr.NewHandler(logger, map[error]resterr.RESTErr{}, resterr.WithFallbackMapper(FromGRPCStatus))
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+	handler.Handle(context.TODO(), writer, status.Error(codes.ResourceExhausted, "quota exceeded"))
+
+	assert.Equal(t, http.StatusTooManyRequests, writer.Code)
+	assert.JSONEq(t, `{"status-code":429,"message":"quota exceeded","grpc_code":8}`, writer.Body.String())
+}
@@ -1,23 +1,94 @@
 package resterr
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
 // Handler handles standard errors by logging them and looking for an equivalent REST error in the error map.
 // Errors that are not mapped result in internal server errors.
 type Handler struct {
-	logger          *slog.Logger
-	internalErrJSON []byte
-	errorMap        sync.Map
-	validationFn    func(restErr RESTErr) error
+	logger            *slog.Logger
+	internalErrStatus int
+	internalErrJSON   []byte
+	errorMap          sync.Map
+	orderMu           sync.Mutex
+	order             atomic.Pointer[[]any]
+	validationFn      func(restErr RESTErr) error
+	unwrapFn          func(err error) []error
+	statusRewriteFn   func(statusCode int) int
+	onHandleFn        func(ctx context.Context, err error)
+	stdErrors         bool
+	extendedStatuses  bool
+	fallbackErr       RESTErr
+	clientDefault     *RESTErr
+	serverDefault     *RESTErr
+	normalizeFn       func(s string) string
+	noStore           bool
+	marshalFn         func(e RESTErr) ([]byte, error)
+	domainFn          func(err error) string
+	domains           map[string]map[error]RESTErr
+	domainMappings    map[string][]mapping
+	errKey            string
+	logRedactions     []*regexp.Regexp
+	restErrKey        string
+	localeKey         any
+	debugKey          any
+	fallbackMappers   []func(err error) (RESTErr, bool)
+	errorIDFn         func() string
+	errorIDWhenFn     func(restErr RESTErr) bool
+	defaultSeverity   bool
+	autoFlush         bool
+	codeMapperFn      func(code int) (RESTErr, bool)
+	retryAfter        map[int]string
+	maxHeaders        int
+	autoErrors        []error
+	reportFn          func(ctx context.Context, err error, restErr RESTErr)
+	metricsHooks      []func(ctx context.Context, restErr RESTErr)
+	reportThreshold   int
+	deprecations      map[error]string
+	responseValidFn   func(body []byte) error
+	emptyDetails      EmptyDetails
+	statusHeader      string
+	panicMapperFn     func(recovered any) (RESTErr, bool)
+	problemDetails    bool
+	problemParamsKey  string
+	requestIDHeader   string
+	requestIDFn       func() string
+	emptyMap          emptyMapPolicy
+	env               string
+	formatKey         any
+	traceparentFn     func(ctx context.Context) string
+	statusFallbacks   map[int]RESTErr
+	routes            []Route
+	routeMappings     [][]mapping
+	summaryMaxBytes   int
+	delayFn           func(restErr RESTErr) time.Duration
+	htmlPages         map[int]string
+	writeFn           func(ctx context.Context, statusCode int, headers http.Header, body []byte) error
+	maxMessageLen     int
+	statusAsString    bool
+	errLogThreshold   int
+	sunset            time.Time
+	variantFn         func(r *http.Request) string
+	variants          map[string]map[error]RESTErr
+	variantMappings   map[string][]mapping
 }
 
 // Option applies custom behavior to the handler.
@@ -30,123 +101,1924 @@ func WithValidationFn(fn func(restErr RESTErr) error) Option {
 	}
 }
 
+// WithUnwrapper is an option to set a custom function for unwrapping errors that use
+// unconventional wrapping, such as a Causes() []error method.
+// The handler expands the error tree with both the standard Unwrap methods and fn
+// before matching it against the error map.
+func WithUnwrapper(fn func(err error) []error) Option {
+	return func(h *Handler) {
+		h.unwrapFn = fn
+	}
+}
+
+// WithStatusCodeRewriter is an option to set a function that rewrites the final status code
+// of every response, such as downgrading all 5xx to 503 behind gateways that alert on them.
+// The status code in the response body is rewritten as well.
+func WithStatusCodeRewriter(fn func(statusCode int) int) Option {
+	return func(h *Handler) {
+		h.statusRewriteFn = fn
+	}
+}
+
+// WithOnHandle is an option to set a function called with every error passed to the handler,
+// before it is resolved. It is useful for tests and tracing that wrap the entry point.
+func WithOnHandle(fn func(ctx context.Context, err error)) Option {
+	return func(h *Handler) {
+		h.onHandleFn = fn
+	}
+}
+
+// WithErrorReporter is an option to set a function reporting errors to an external error tracker,
+// such as Sentry, with the original error and the REST error it resolved to.
+// It is called for server errors only, unless configured with WithErrorReportThreshold,
+// and before the response is written so that reports are not lost when writing fails.
+func WithErrorReporter(fn func(ctx context.Context, err error, restErr RESTErr)) Option {
+	return func(h *Handler) {
+		h.reportFn = fn
+	}
+}
+
+// WithMetricsHook is an option to add a function called with every REST error resolved for
+// a handled error, such as to count responses by status code. Hooks are called in the order
+// they were added, before the response is written.
+func WithMetricsHook(fn func(ctx context.Context, restErr RESTErr)) Option {
+	return func(h *Handler) {
+		h.metricsHooks = append(h.metricsHooks, fn)
+	}
+}
+
+// WithErrorReportThreshold is an option to set the lowest status code reported by the
+// error reporter. It defaults to 500 Internal Server Error. The threshold applies to the status
+// code errors resolve to, before the status code rewriter and the status of the header set with
+// WithStatusFromHeader, so that server errors rewritten to client errors are still reported.
+func WithErrorReportThreshold(statusCode int) Option {
+	return func(h *Handler) {
+		h.reportThreshold = statusCode
+	}
+}
+
+// DeprecateMapping is an option to deprecate the mapping of key in the error map, for phasing
+// out an error gradually. The mapped REST error is still returned, with a Warning header
+// holding note, and handling the error logs a deprecation warning. NewHandler fails
+// when key is not in the error map.
+func DeprecateMapping(key error, note string) Option {
+	return func(h *Handler) {
+		if h.deprecations == nil {
+			h.deprecations = make(map[error]string)
+		}
+		h.deprecations[key] = note
+	}
+}
+
+// WithResponseValidator is an option to set a function validating the body of every response
+// before it is written, including NDJSON items, such as against a JSON Schema in non-production
+// environments. Responses failing validation are logged as errors and replaced by the internal
+// server error, which is not validated. It adds a cost to every response, so it is best kept
+// out of production.
+func WithResponseValidator(fn func(body []byte) error) Option {
+	return func(h *Handler) {
+		h.responseValidFn = fn
+	}
+}
+
+// WithStatusFromHeader is an option to let HandleRequest override the status code of the resolved
+// REST error with the one in the name request header, such as a status set by an upstream service
+// in a trusted internal network. Values that are not numbers between 400 and 599 are ignored.
+// The status code rewriter set with WithStatusCodeRewriter applies to the status of the header,
+// which the metrics hooks observe. The error reporter threshold applies to the resolved status.
+func WithStatusFromHeader(name string) Option {
+	return func(h *Handler) {
+		h.statusHeader = name
+	}
+}
+
+// WithEchoRequestID is an option to let HandleRequest echo the request ID in the headerName
+// request header, such as X-Request-ID, back on the response and log it along with the error,
+// for correlating responses with logs. Requests without the header get no request ID,
+// unless a generator is set with WithRequestIDGenerator.
+func WithEchoRequestID(headerName string) Option {
+	return func(h *Handler) {
+		h.requestIDHeader = headerName
+	}
+}
+
+// WithRequestIDGenerator is an option to set a function generating the request ID
+// of requests without one, when echoing request IDs with WithEchoRequestID.
+// A nil fn uses DefaultIDGenerator.
+func WithRequestIDGenerator(fn func() string) Option {
+	return func(h *Handler) {
+		h.requestIDFn = fn
+		if fn == nil {
+			h.requestIDFn = DefaultIDGenerator()
+		}
+	}
+}
+
+// emptyMapPolicy is how NewHandler treats an empty error map.
+type emptyMapPolicy int
+
+const (
+	emptyMapAllow emptyMapPolicy = iota
+	emptyMapWarn
+	emptyMapRequire
+)
+
+// String returns the name of p, "allow", "warn" or "require".
+func (p emptyMapPolicy) String() string {
+	switch p {
+	case emptyMapWarn:
+		return "warn"
+	case emptyMapRequire:
+		return "require"
+	default:
+		return "allow"
+	}
+}
+
+// WithWarnOnEmptyMap is an option to log a warning when the handler is created without mappings,
+// which results in internal server errors for every error and often means the error map was
+// forgotten. Mappings added with WithDomainRouter and AutoRegister count, those of
+// WithStandardErrors do not.
+func WithWarnOnEmptyMap() Option {
+	return func(h *Handler) {
+		h.emptyMap = emptyMapWarn
+	}
+}
+
+// WithRequireNonEmptyMap is an option to make NewHandler fail when the handler is created
+// without mappings, counted as by WithWarnOnEmptyMap.
+func WithRequireNonEmptyMap() Option {
+	return func(h *Handler) {
+		h.emptyMap = emptyMapRequire
+	}
+}
+
+// WithEnvironment is an option to set the environment the handler runs in, such as "production"
+// or "staging", selecting the message of REST errors from their MessagesByEnv.
+// Errors without a message for the environment keep their message.
+func WithEnvironment(env string) Option {
+	return func(h *Handler) {
+		h.env = env
+	}
+}
+
+// WithSummaryOnLargeResponse is an option to write a summary instead of responses with details
+// larger than maxBytes: the REST error without its details, with a message giving their number
+// and the details-truncated member set to true, as in {"status-code":422,"message":"120 validation
+// errors","details-truncated":true}, and without the X-Error-Count header of responses with details.
+// Clients can then get the details another way, such as from a follow-up endpoint. Custom formats,
+// such as the one of WithMinimalFormat, have no details-truncated member.
+func WithSummaryOnLargeResponse(maxBytes int) Option {
+	return func(h *Handler) {
+		h.summaryMaxBytes = maxBytes
+	}
+}
+
+// WithResponseDelay is an option to delay error responses by the duration fn returns for them,
+// to simulate slow error paths in chaos experiments, such as to verify client timeouts and retries.
+// It is meant for staging environments, not production. Waiting stops as soon as the request
+// context is done, so that canceled requests are not held. Zero or negative durations do not delay.
+func WithResponseDelay(fn func(restErr RESTErr) time.Duration) Option {
+	return func(h *Handler) {
+		h.delayFn = fn
+	}
+}
+
+// WithStatusAsString is an option to serialize the status-code member of the default JSON format
+// as a string, such as "404", for strict clients and gateways that require it. The mapped errors
+// are marshaled accordingly at initialization. Problem details documents keep a numeric status,
+// as RFC 9457 requires.
+func WithStatusAsString() Option {
+	return func(h *Handler) {
+		h.statusAsString = true
+	}
+}
+
+// WithDeprecation is an option to mark the API version served by the handler as deprecated,
+// with the Deprecation: true and Sunset headers on every error response, the latter holding
+// sunset as an HTTP-date. Clients and API gateways scrape them to warn consumers. It complements
+// DeprecateMapping, which deprecates single mappings with a Warning header.
+func WithDeprecation(sunset time.Time) Option {
+	return func(h *Handler) {
+		h.sunset = sunset
+	}
+}
+
+// WithStandardErrors is an option to map common standard library errors to REST errors.
+// Truncated request bodies (io.ErrUnexpectedEOF) and empty request bodies (io.EOF)
+// result in 400 Bad Request. Mappings in the error map take precedence.
+func WithStandardErrors() Option {
+	return func(h *Handler) {
+		h.stdErrors = true
+	}
+}
+
+// WithExtendedStatuses is an option to map the errors of the HTTP statuses that are rarely handled:
+// ErrHeaderTooLarge results in 431 Request Header Fields Too Large, ErrRateLimited in
+// 429 Too Many Requests and ErrLegalBlock in 451 Unavailable For Legal Reasons.
+// Mappings in the error map take precedence.
+func WithExtendedStatuses() Option {
+	return func(h *Handler) {
+		h.extendedStatuses = true
+	}
+}
+
+// WithFallbackRESTErr is an option to set the REST error used for errors that are not mapped
+// but signal their own status code by implementing StatusCoder.
+// The status code of e is replaced by the one signaled by the error, and an empty message
+// defaults to the standard status text. Truly unknown errors still result in internal server errors.
+func WithFallbackRESTErr(e RESTErr) Option {
+	return func(h *Handler) {
+		h.fallbackErr = e
+	}
+}
+
+// WithClientErrorDefault is an option to set the REST error used for errors that are not mapped
+// but signal a client error status code (4xx) by implementing StatusCoder, such as a generic
+// 400 Bad Request for any error that looks like a client error. Its status code is kept,
+// unless it is zero, in which case the signaled one is used.
+// Exact mappings, from the error map, the domains, the mappers and WithInternalErrorForStatus,
+// take precedence over it, and it takes precedence over the fallback REST error.
+// Errors signaling no status code still result in the internal server error.
+func WithClientErrorDefault(e RESTErr) Option {
+	return func(h *Handler) {
+		h.clientDefault = &e
+	}
+}
+
+// WithServerErrorDefault is an option to set the REST error used for errors that are not mapped
+// but signal a server error status code (5xx) by implementing StatusCoder, with the same
+// precedence as the one set with WithClientErrorDefault.
+func WithServerErrorDefault(e RESTErr) Option {
+	return func(h *Handler) {
+		h.serverDefault = &e
+	}
+}
+
+// WithErrorStringNormalizer is an option to enable matching unmapped errors by their message.
+// When no mapped error matches with errors.Is, an error matches a mapped error whose message
+// is equal to its own once both are normalized by fn, which typically strips variable parts
+// such as IDs or timestamps. Matching with errors.Is is not affected.
+func WithErrorStringNormalizer(fn func(s string) string) Option {
+	return func(h *Handler) {
+		h.normalizeFn = fn
+	}
+}
+
+// WithNoStore is an option to set the "Cache-Control: no-store" header on all error responses,
+// preventing intermediaries from caching them.
+func WithNoStore() Option {
+	return func(h *Handler) {
+		h.noStore = true
+	}
+}
+
+// WithMinimalFormat is an option to write the most compact body possible for bandwidth-sensitive
+// clients: a JSON object holding only the message under key, which defaults to "m".
+// The status code is only sent in the HTTP status line.
+func WithMinimalFormat(key string) Option {
+	if key == "" {
+		key = "m"
+	}
+
+	return func(h *Handler) {
+		h.problemDetails = false
+		h.marshalFn = func(e RESTErr) ([]byte, error) {
+			return json.Marshal(map[string]string{key: e.Message})
+		}
+	}
+}
+
+// WithDomainRouter is an option to split the error map by domain, such as the modules of a
+// modular monolith. The domain of an error is given by domainFn, and the error is matched
+// against the error map of that domain first, falling back to the handler's error map
+// when the domain is unknown or has no matching error. An error matching several mappings
+// of its domain resolves to the mapped error whose message sorts first.
+func WithDomainRouter(domainFn func(err error) string, domains map[string]map[error]RESTErr) Option {
+	return func(h *Handler) {
+		h.domainFn = domainFn
+		h.domains = make(map[string]map[error]RESTErr, len(domains))
+		for domain, domainMap := range domains {
+			h.domains[domain] = maps.Clone(domainMap)
+		}
+	}
+}
+
+// WithLogRedactor is an option to replace the matches of patterns with *** in the original errors
+// when they are logged, for errors wrapping payloads with secrets, such as
+// regexp.MustCompile(`Bearer [\w.-]+`) for bearer tokens. The REST errors logged with them
+// and recovered panic values are redacted too. Responses are not affected.
+func WithLogRedactor(patterns []*regexp.Regexp) Option {
+	return func(h *Handler) {
+		h.logRedactions = append(h.logRedactions, patterns...)
+	}
+}
+
+// WithMaxMessageLength is an option to truncate the messages of REST errors longer than n characters
+// at write time, ending them with an ellipsis, so that errors echoing huge inputs, such as SQL queries,
+// do not bloat responses, NDJSON items and trailers. The original errors are truncated the same way
+// when logged.
+// Truncation never splits a multi-byte character. Messages of mapped errors that are too long
+// are marshaled on each write.
+func WithMaxMessageLength(n int) Option {
+	return func(h *Handler) {
+		h.maxMessageLen = n
+	}
+}
+
+// WithErrorLogThreshold is an option to set the lowest status code of the errors logged at error
+// level when they are resolved, the others being logged at info level. It defaults to 500
+// Internal Server Error, so that unmapped errors and errors mapped to server errors are logged
+// at error level, even when the status code rewriter turns them into client errors.
+func WithErrorLogThreshold(statusCode int) Option {
+	return func(h *Handler) {
+		h.errLogThreshold = statusCode
+	}
+}
+
+// WithLogKeys is an option to rename the log attributes holding the original error
+// and the REST error it resolved to, which default to "error" and "rest-error".
+// The errors failing to marshal or write responses are logged under the original error key.
+func WithLogKeys(originalKey, restKey string) Option {
+	return func(h *Handler) {
+		h.errKey = originalKey
+		h.restErrKey = restKey
+	}
+}
+
+// WithLocaleFromContext is an option to localize REST error messages with the locale
+// stored in the context under key, such as a user's preferred language.
+// The locale must be a string matching a key of the REST error's Translations,
+// otherwise the default message is used.
+func WithLocaleFromContext(key any) Option {
+	return func(h *Handler) {
+		h.localeKey = key
+	}
+}
+
+// WithDebugFromContext is an option to include debug information in the responses to
+// requests whose context holds a truthy debug flag under key, such as requests carrying
+// a signed debug token during incident triage. The flag is a bool, or a string parsed
+// by strconv.ParseBool. The debug information holds the message of the original error
+// and of the errors it wraps, and is always encoded as JSON. Other requests get
+// the sanitized REST error.
+func WithDebugFromContext(key any) Option {
+	return func(h *Handler) {
+		h.debugKey = key
+	}
+}
+
+// WithInternalErrorForStatus is an option to set the REST errors by status code used for errors
+// resolved programmatically to a status code without a message: StatusCoder errors, and REST errors
+// from fallback and code mappers. For instance, a 502 can say "bad gateway" and a 503 "service
+// unavailable" instead of sharing one fallback message. They take precedence over the fallback
+// REST error. Truly unknown errors still result in the internal server error.
+func WithInternalErrorForStatus(errs map[int]RESTErr) Option {
+	return func(h *Handler) {
+		h.statusFallbacks = maps.Clone(errs)
+	}
+}
+
+// WithFallbackMapper is an option to add a function that maps errors programmatically
+// when they are not in the error map, such as errors from a third-party library.
+// Mappers are consulted in the order they were added, before StatusCoder errors.
+func WithFallbackMapper(fn func(err error) (RESTErr, bool)) Option {
+	return func(h *Handler) {
+		h.fallbackMappers = append(h.fallbackMappers, fn)
+	}
+}
+
+// WithErrorID is an option to include an ID generated by fn in server error responses,
+// which is also logged along with the original error so that support teams can trace
+// a response back to its cause. Errors with an ID are marshaled on each write.
+// A nil fn uses DefaultIDGenerator.
+func WithErrorID(fn func() string) Option {
+	return func(h *Handler) {
+		h.errorIDFn = fn
+		if fn == nil {
+			h.errorIDFn = DefaultIDGenerator()
+		}
+	}
+}
+
+// WithErrorIDWhen is an option to choose which REST errors get an ID when WithErrorID is set,
+// such as notable client errors. It defaults to server errors only.
+func WithErrorIDWhen(fn func(restErr RESTErr) bool) Option {
+	return func(h *Handler) {
+		h.errorIDWhenFn = fn
+	}
+}
+
+// WithDefaultSeverity is an option to derive the severity of REST errors that have none
+// from their status code, as given by DefaultSeverity.
+func WithDefaultSeverity() Option {
+	return func(h *Handler) {
+		h.defaultSeverity = true
+	}
+}
+
+// EmptyDetails is how REST errors without details are serialized.
+type EmptyDetails int
+
+const (
+	// EmptyDetailsOmit omits the details member, which is the default.
+	EmptyDetailsOmit EmptyDetails = iota
+	// EmptyDetailsNull serializes the details member as null.
+	EmptyDetailsNull
+	// EmptyDetailsArray serializes the details member as an empty array.
+	EmptyDetailsArray
+)
+
+// String returns the name of b: "omit", "null" or "array".
+func (b EmptyDetails) String() string {
+	switch b {
+	case EmptyDetailsNull:
+		return "null"
+	case EmptyDetailsArray:
+		return "array"
+	default:
+		return "omit"
+	}
+}
+
+// WithEmptyDetailsBehavior is an option to set how REST errors without details are serialized,
+// so that API owners can standardize on what their clients handle. It applies to the
+// default JSON format only, not to custom marshal functions.
+func WithEmptyDetailsBehavior(b EmptyDetails) Option {
+	return func(h *Handler) {
+		h.emptyDetails = b
+	}
+}
+
+// WithAutoFlush is an option to flush error responses right after writing them, when the
+// writer implements http.Flusher, so they are not held back by buffering middleware.
+func WithAutoFlush() Option {
+	return func(h *Handler) {
+		h.autoFlush = true
+	}
+}
+
+// WithCodeMapper is an option to map errors implementing Coder to REST errors by their
+// application-specific code, when they are not in the error map. The original code can be
+// sent to clients by setting it as the Code of the returned REST error.
+func WithCodeMapper(fn func(code int) (RESTErr, bool)) Option {
+	return func(h *Handler) {
+		h.codeMapperFn = fn
+	}
+}
+
+// WithDefaultRetryAfter is an option to set a Retry-After header, rounded up to the second,
+// on all error responses with statusCode, such as 429 Too Many Requests or 503 Service Unavailable.
+// A Retry-After header set on the REST error takes precedence. A zero or negative d sets no header,
+// as Retry-After cannot be negative.
+func WithDefaultRetryAfter(statusCode int, d time.Duration) Option {
+	return func(h *Handler) {
+		if d <= 0 {
+			delete(h.retryAfter, statusCode)
+			return
+		}
+
+		if h.retryAfter == nil {
+			h.retryAfter = make(map[int]string)
+		}
+		h.retryAfter[statusCode] = retryAfterSeconds(d)
+	}
+}
+
+// WithMaxHeaders is an option to limit the number of custom headers set on a response,
+// which are the headers of the REST error and the defaults for its status code.
+// Headers over the limit are dropped in lexical order, keeping the first n, and logged as a warning.
+// There is no limit by default.
+func WithMaxHeaders(n int) Option {
+	return func(h *Handler) {
+		h.maxHeaders = n
+	}
+}
+
 var logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
 
-// NewHandler returns a REST error handler.
-// It pre-processes the JSON values for REST errors.
-func NewHandler(logger *slog.Logger, errMap map[error]RESTErr, opts ...Option) (*Handler, error) {
-	internalErrJSON, err := json.Marshal(internalErr)
-	if err != nil {
-		return nil, fmt.Errorf("could not marshal internal error: %w", err)
+// NewHandler returns a REST error handler.
+// It pre-processes the JSON values for REST errors.
+// Errors matching several mappings of errMap, such as joined errors, resolve to the mapping
+// whose error message comes first in lexical order, so that they resolve the same way on every run.
+func NewHandler(logger *slog.Logger, errMap map[error]RESTErr, opts ...Option) (*Handler, error) {
+	h := Handler{
+		logger:          logger.WithGroup("resterr-handler"),
+		errorMap:        sync.Map{},
+		errKey:          "error",
+		restErrKey:      "rest-error",
+		reportThreshold: http.StatusInternalServerError,
+		errLogThreshold: http.StatusInternalServerError,
+	}
+
+	for _, o := range opts {
+		o(&h)
+	}
+
+	ie := h.finalize(internalErr)
+
+	internalErrJSON, err := h.marshal(ie)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal internal error: %w", err)
+	}
+	h.internalErrStatus = ie.StatusCode
+	h.internalErrJSON = internalErrJSON
+
+	if h.emptyMap != emptyMapAllow && len(errMap) == 0 && len(h.autoErrors) == 0 && !h.hasDomainMappings() {
+		if h.emptyMap == emptyMapRequire {
+			return nil, errors.New("error map is empty")
+		}
+		h.logger.Warn("Error map is empty, every error results in an internal server error.")
+	}
+
+	if h.stdErrors {
+		errMap = withDefaults(errMap, standardErrors)
+	}
+
+	if h.extendedStatuses {
+		errMap = withDefaults(errMap, extendedErrors)
+	}
+
+	if len(h.autoErrors) > 0 {
+		registered, err := autoRegistered(errMap, h.autoErrors)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(registered, errMap)
+		errMap = registered
+	}
+
+	for k := range h.deprecations {
+		if _, ok := errMap[k]; !ok {
+			return nil, fmt.Errorf("could not deprecate unmapped error '%v'", k)
+		}
+	}
+
+	keys, err := sortedKeys(errMap)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		e := errMap[k]
+		if note, ok := h.deprecations[k]; ok {
+			e = deprecate(e, note)
+		}
+
+		prepared, err := h.prepare(e)
+		if err != nil {
+			return nil, err
+		}
+		h.store(k, prepared)
+	}
+
+	h.domainMappings = make(map[string][]mapping, len(h.domains))
+	for domain, domainMap := range h.domains {
+		mappings, err := h.prepareMappings(domainMap)
+		if err != nil {
+			return nil, fmt.Errorf("could not prepare domain '%s': %w", domain, err)
+		}
+		h.domainMappings[domain] = mappings
+	}
+
+	h.routeMappings = make([][]mapping, len(h.routes))
+	for i, route := range h.routes {
+		mappings, err := h.prepareMappings(route.ErrorMap)
+		if err != nil {
+			return nil, fmt.Errorf("could not prepare route '%s': %w", route.name(), err)
+		}
+		h.routeMappings[i] = mappings
+	}
+
+	h.variantMappings = make(map[string][]mapping, len(h.variants))
+	for tag, variantMap := range h.variants {
+		keys, err := sortedKeys(variantMap)
+		if err != nil {
+			return nil, fmt.Errorf("could not prepare variant '%s': %w", tag, err)
+		}
+
+		for _, k := range keys {
+			pe, err := h.prepare(variantMap[k])
+			if err != nil {
+				return nil, fmt.Errorf("could not prepare variant '%s' of '%v': %w", tag, k, err)
+			}
+			h.variantMappings[tag] = append(h.variantMappings[tag], mapping{err: k, restErr: pe})
+		}
+	}
+	return &h, nil
+}
+
+// deprecate adds the deprecation note to e and to its headers, as a miscellaneous persistent warning.
+func deprecate(e RESTErr, note string) RESTErr {
+	e.Headers = e.Headers.Clone()
+	if e.Headers == nil {
+		e.Headers = make(http.Header, 1)
+	}
+	e.Headers.Add("Warning", fmt.Sprintf("299 - %q", note))
+	e.deprecation = note
+	return e
+}
+
+// errAttr returns the log attribute of the original error err, redacted with the patterns
+// of WithLogRedactor.
+func (h *Handler) errAttr(err error) slog.Attr {
+	return slog.String(h.errKey, h.logMessage(err))
+}
+
+// logMessage returns the message of err as logged, redacted and truncated to the maximum message length.
+func (h *Handler) logMessage(err error) string {
+	return h.redact(err.Error())
+}
+
+// redact returns msg redacted with the patterns of WithLogRedactor and truncated to the maximum
+// message length, for the messages logged along with the original errors.
+func (h *Handler) redact(msg string) string {
+	for _, re := range h.logRedactions {
+		msg = re.ReplaceAllLiteralString(msg, "***")
+	}
+	return truncate(msg, h.maxMessageLen)
+}
+
+// truncate returns s cut to n characters, the last being an ellipsis, when it is longer.
+// A zero or negative n does not truncate.
+func truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	var runes int
+	for i := range s {
+		if runes == n-1 {
+			return s[:i] + "…"
+		}
+		runes++
+	}
+	return s
+}
+
+// mapping maps an error to a REST error in the error maps given as options, which are matched
+// in the order of their mappings.
+type mapping struct {
+	err     error
+	restErr RESTErr
+}
+
+// sortedKeys returns the errors of errMap sorted by message. Maps have no order, so that errors
+// matching several mappings would otherwise not resolve the same way on every run.
+// Errors with the same message are sorted by type name, then by the code, status code and
+// message of their REST errors; errors equal on all of these map to interchangeable REST errors.
+// Nil errors cannot be mapped and return an error.
+func sortedKeys(errMap map[error]RESTErr) ([]error, error) {
+	keys := make([]error, 0, len(errMap))
+	for k := range errMap {
+		if k == nil {
+			return nil, errors.New("could not map nil error")
+		}
+		keys = append(keys, k)
+	}
+
+	slices.SortStableFunc(keys, func(a, b error) int {
+		return cmp.Or(
+			cmp.Compare(a.Error(), b.Error()),
+			cmp.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)),
+			cmp.Compare(errMap[a].Code, errMap[b].Code),
+			cmp.Compare(errMap[a].StatusCode, errMap[b].StatusCode),
+			cmp.Compare(errMap[a].Message, errMap[b].Message),
+		)
+	})
+	return keys, nil
+}
+
+// prepareMappings prepares the REST errors of errMap, returned as mappings sorted
+// by the message of their errors like the error map.
+func (h *Handler) prepareMappings(errMap map[error]RESTErr) ([]mapping, error) {
+	keys, err := sortedKeys(errMap)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make([]mapping, 0, len(keys))
+	for _, k := range keys {
+		prepared, err := h.prepare(errMap[k])
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping{err: k, restErr: prepared})
+	}
+	return mappings, nil
+}
+
+// withDefaults returns errMap merged with the defaults mappings, which errMap takes precedence over.
+func withDefaults(errMap, defaults map[error]RESTErr) map[error]RESTErr {
+	merged := make(map[error]RESTErr, len(defaults)+len(errMap))
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, errMap)
+	return merged
+}
+
+// hasDomainMappings reports whether any domain error map has mappings.
+func (h *Handler) hasDomainMappings() bool {
+	for _, domainMap := range h.domains {
+		if len(domainMap) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare validates a REST error from an error map and pre-marshals it.
+func (h *Handler) prepare(e RESTErr) (RESTErr, error) {
+	if h.validationFn != nil {
+		if err := h.validationFn(e); err != nil {
+			return RESTErr{}, fmt.Errorf("validation failed for REST error '%v': %w", e, err)
+		}
+	}
+
+	e = h.finalize(e)
+
+	res, err := h.marshal(e)
+	if err != nil {
+		return RESTErr{}, fmt.Errorf("could not marshal REST error '%v': %w", e, err)
+	}
+	e.json = res
+
+	if len(e.Translations) > 0 {
+		e.localized = make(map[string][]byte, len(e.Translations))
+		for locale, msg := range e.Translations {
+			le := e
+			le.Message = msg
+
+			res, err := h.marshal(le)
+			if err != nil {
+				return RESTErr{}, fmt.Errorf("could not marshal REST error '%v' for locale '%s': %w", e, locale, err)
+			}
+			e.localized[locale] = res
+		}
+	}
+
+	e.prepared = true
+	return e, nil
+}
+
+// Writer defines the interface for writing error data.
+type Writer interface {
+	Write([]byte) (int, error)
+	WriteHeader(statusCode int)
+	Header() http.Header
+}
+
+// StatusCodes returns the sorted distinct status codes of the REST errors the handler
+// is configured with, including the internal server error, the mappings of domains, routes
+// and variants, and the mappings added by options such as WithStandardErrors.
+// The status codes are the ones written, after rewriting.
+func (h *Handler) StatusCodes() []int {
+	codes := []int{h.internalErrStatus}
+
+	h.rangeMappings(func(_ any, re RESTErr) bool {
+		codes = append(codes, re.StatusCode)
+		return true
+	})
+
+	for _, mappings := range h.domainMappings {
+		for _, m := range mappings {
+			codes = append(codes, m.restErr.StatusCode)
+		}
+	}
+
+	for _, mappings := range h.routeMappings {
+		for _, m := range mappings {
+			codes = append(codes, m.restErr.StatusCode)
+		}
+	}
+
+	for _, mappings := range h.variantMappings {
+		for _, m := range mappings {
+			codes = append(codes, m.restErr.StatusCode)
+		}
+	}
+
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+// Resolve returns the REST error Handle would write for err, which is the internal server error
+// when err is unmapped, without writing it nor calling the error reporter and metrics hooks,
+// such as for tests asserting how errors are mapped. It returns the zero RESTErr for nil errors.
+func (h *Handler) Resolve(ctx context.Context, err error) RESTErr {
+	if err == nil {
+		return RESTErr{}
+	}
+
+	if h.onHandleFn != nil {
+		h.onHandleFn(ctx, err)
+	}
+
+	restErr, res := h.match(ctx, err)
+	h.logResolution(ctx, err, restErr, res)
+
+	if !restErr.prepared {
+		restErr = h.finalize(restErr)
+	}
+	return h.localize(ctx, restErr)
+}
+
+// Handle logs the original error and checks for the error in the error -> REST error map
+// provided at initialization. If the error is present in the map, it writes the REST error as JSON.
+// Otherwise, it writes a JSON indicating an internal server error.
+// Nil errors are ignored and nothing is written.
+func (h *Handler) Handle(ctx context.Context, w Writer, err error) {
+	h.handle(ctx, w, err)
+}
+
+// TryHandle behaves like Handle and reports whether a response was written, which is not
+// the case for nil errors. It lets middleware tell a handled error from nothing to do.
+func (h *Handler) TryHandle(ctx context.Context, w Writer, err error) bool {
+	_, wrote := h.handle(ctx, w, err)
+	return wrote
+}
+
+// HandleInto behaves like Handle and stores the REST error that was written into out,
+// which is the internal server error when err is unmapped. It lets middleware inspect
+// the response without parsing it back. out is left untouched for nil errors.
+func (h *Handler) HandleInto(ctx context.Context, w Writer, err error, out *RESTErr) {
+	restErr, wrote := h.handle(ctx, w, err)
+	if wrote && out != nil {
+		*out = restErr
+	}
+}
+
+// handle resolves and writes err, reporting whether it did. Nil errors are ignored.
+func (h *Handler) handle(ctx context.Context, w Writer, err error) (RESTErr, bool) {
+	if err == nil {
+		h.logger.DebugContext(ctx, "Ignoring nil error.")
+		return RESTErr{}, false
+	}
+
+	w, deliver := h.transport(ctx, w)
+	defer deliver()
+
+	return h.write(ctx, w, h.resolve(ctx, err)), true
+}
+
+// HandleStatusMessage writes a REST error with statusCode and message, for errors without
+// a sentinel to map. An empty message defaults to the standard status text. The REST error
+// is written like in Handle, with the configured format, headers and hooks.
+func (h *Handler) HandleStatusMessage(ctx context.Context, w Writer, statusCode int, message string) {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	h.handle(ctx, w, RESTErr{
+		StatusCode: statusCode,
+		Message:    message,
+	})
+}
+
+// HandleErrors writes a single REST error with statusCode that aggregates errs in its details,
+// such as the validation errors of a form submission. Each error is resolved like in Handle:
+// its details are added as is, or its message when it has none. Unmapped errors add
+// the internal server error message. Nil errors are skipped, and nothing is written
+// when all errors are nil. The aggregate is logged once, with the errors it is made of.
+func (h *Handler) HandleErrors(ctx context.Context, w Writer, statusCode int, errs ...error) {
+	e := RESTErr{
+		StatusCode: statusCode,
+		Message:    http.StatusText(statusCode),
+	}
+
+	var msgs []string
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		msgs = append(msgs, h.logMessage(err))
+
+		re, _, found := h.find(ctx, err)
+		if !found {
+			e.Details = append(e.Details, Detail{Message: internalErr.Message})
+			continue
+		}
+
+		re = h.localize(ctx, re)
+
+		if len(re.Details) > 0 {
+			e.Details = append(e.Details, re.Details...)
+			continue
+		}
+		e.Details = append(e.Details, Detail{Message: re.Message})
+	}
+
+	if len(msgs) == 0 {
+		h.logger.DebugContext(ctx, "Ignoring nil errors.")
+		return
+	}
+
+	e.LogAttrs = []slog.Attr{slog.Any("errors", msgs)}
+	h.handle(ctx, w, e)
+}
+
+// ndjsonItem is a line of an NDJSON response reporting the error of the input at Index.
+type ndjsonItem struct {
+	Index int             `json:"index"`
+	Error json.RawMessage `json:"error"`
+}
+
+// HandleNDJSONItem resolves err like Handle and writes it as a single line of an NDJSON
+// response, {"index":<index>,"error":<REST error>}, for the input line at index.
+// The line is flushed right away when the writer supports it.
+// It assumes the NDJSON response is already in progress, so no status code or headers are written.
+// Nil errors write no line.
+func (h *Handler) HandleNDJSONItem(ctx context.Context, w Writer, index int, err error) {
+	if err == nil {
+		h.logger.DebugContext(ctx, "Ignoring nil error.")
+		return
+	}
+
+	restErr := h.truncateMessage(h.resolve(ctx, err))
+
+	_, body, err := h.response(restErr)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Failed to marshal NDJSON error.", slog.String("source-error", restErr.Error()), slog.String(h.errKey, err.Error()))
+		body = h.internalErrJSON
+	} else if !h.validResponse(ctx, restErr, body) {
+		body = h.internalErrJSON
+	}
+
+	line, err := json.Marshal(ndjsonItem{Index: index, Error: body})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Failed to marshal NDJSON line.", slog.String("source-error", restErr.Error()), slog.String(h.errKey, err.Error()))
+		return
+	}
+
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		h.logger.ErrorContext(ctx, "Failed to write NDJSON error.", slog.String("source-error", restErr.Error()), slog.String(h.errKey, err.Error()))
+		return
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleRequest behaves like Handle, using the request context, and additionally
+// honors conditional GET and HEAD requests. When the resolved REST error has a LastModified
+// time, it is sent as the Last-Modified header and a request carrying an If-Modified-Since
+// header that is not older than it is answered with 304 Not Modified and no body.
+// Responses to HEAD requests never have a body, but announce its length with Content-Length.
+// Clients preferring HTML get the pages set with WithDefaultHTMLErrorPage for server errors.
+func (h *Handler) HandleRequest(w Writer, r *http.Request, err error) {
+	ctx := r.Context()
+
+	if err == nil {
+		h.logger.DebugContext(ctx, "Ignoring nil error.")
+		return
+	}
+
+	w, deliver := h.transport(ctx, w)
+	defer deliver()
+
+	if id := h.echoRequestID(w, r); id != "" {
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		r = r.WithContext(ctx)
+	}
+
+	if r.Method == http.MethodHead {
+		hw := &headWriter{Writer: w}
+		defer hw.flush()
+		w = hw
+	}
+
+	restErr := h.resolveRequest(r, err)
+	restErr = h.resolveProblemURIs(r, restErr)
+
+	if !restErr.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", restErr.LastModified.UTC().Format(http.TimeFormat))
+
+		if notModified(r, restErr.LastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if len(h.htmlPages) > 0 {
+		varyAccept(w.Header())
+	}
+
+	if page, ok := h.htmlPage(r, restErr); ok {
+		h.writeHTML(ctx, w, restErr, page)
+		return
+	}
+	h.write(ctx, w, restErr)
+}
+
+// requestIDKey is the context key of the request ID echoed by HandleRequest,
+// which is logged along with the resolution of the error.
+type requestIDKey struct{}
+
+// echoRequestID sets the request ID of r, or a generated one, on the response and returns it.
+func (h *Handler) echoRequestID(w Writer, r *http.Request) string {
+	if h.requestIDHeader == "" {
+		return ""
+	}
+
+	id := r.Header.Get(h.requestIDHeader)
+	if id == "" && h.requestIDFn != nil {
+		id = h.requestIDFn()
+	}
+
+	if id != "" {
+		w.Header().Set(h.requestIDHeader, id)
+	}
+	return id
+}
+
+// statusFromHeader overrides the status code of e with the one in the configured request header,
+// which the status code rewriter applies to like to any other status.
+func (h *Handler) statusFromHeader(r *http.Request, e RESTErr) RESTErr {
+	if h.statusHeader == "" {
+		return e
+	}
+
+	v := r.Header.Get(h.statusHeader)
+	if v == "" {
+		return e
+	}
+
+	statusCode, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || statusCode < http.StatusBadRequest || statusCode > 599 {
+		h.logger.WarnContext(r.Context(), "Ignoring invalid status code header.", slog.String("header", h.statusHeader), slog.String("value", v))
+		return e
+	}
+
+	if statusCode == e.StatusCode {
+		return e
+	}
+
+	e = e.withStatus(statusCode)
+	e.json, e.localized = nil, nil
+
+	// REST errors that are not prepared have their status rewritten when they are finalized.
+	if !e.prepared {
+		return e
+	}
+	return h.rewriteStatus(e)
+}
+
+// HandleTrailer resolves err like Handle but reports it through the trailerName HTTP trailer,
+// as "<status code> <message>", instead of the response status and body.
+// It is meant for errors that occur after a chunked response has started. The trailer
+// must be declared with the Trailer header before the first write to the response,
+// otherwise it is silently dropped. Nil errors set no trailer.
+func (h *Handler) HandleTrailer(ctx context.Context, w Writer, err error, trailerName string) {
+	if err == nil {
+		h.logger.DebugContext(ctx, "Ignoring nil error.")
+		return
+	}
+
+	restErr := h.truncateMessage(h.resolve(ctx, err))
+
+	w.Header().Set(trailerName, fmt.Sprintf("%d %s", restErr.StatusCode, restErr.Message))
+}
+
+// headWriter discards the body of responses to HEAD requests.
+// It holds the status code back until the body is known, so that its length
+// can be announced with the Content-Length header.
+type headWriter struct {
+	Writer
+	statusCode  int
+	wroteHeader bool
+}
+
+func (hw *headWriter) WriteHeader(statusCode int) {
+	if hw.statusCode == 0 {
+		hw.statusCode = statusCode
+	}
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	if !hw.wroteHeader {
+		hw.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		hw.flush()
+	}
+	return len(b), nil
+}
+
+// flush writes the status code held back, if not already written.
+func (hw *headWriter) flush() {
+	if hw.wroteHeader || hw.statusCode == 0 {
+		return
+	}
+	hw.wroteHeader = true
+	hw.Writer.WriteHeader(hw.statusCode)
+}
+
+// resolve looks up the REST error for err, which is the internal server error when err
+// is unmapped, and adapts it to the context.
+func (h *Handler) resolve(ctx context.Context, err error) RESTErr {
+	if h.onHandleFn != nil {
+		h.onHandleFn(ctx, err)
+	}
+
+	restErr, res := h.match(ctx, err)
+	return h.conclude(ctx, err, restErr, res)
+}
+
+// conclude logs how err was resolved to restErr and adapts restErr to the context.
+func (h *Handler) conclude(ctx context.Context, err error, restErr RESTErr, res resolution) RESTErr {
+	h.logResolution(ctx, err, restErr, res)
+	return h.adapt(ctx, err, restErr)
+}
+
+// adapt adapts the REST error resolved for err to the context.
+func (h *Handler) adapt(ctx context.Context, err error, restErr RESTErr) RESTErr {
+	// Errors from the error map were already finalized at initialization.
+	if !restErr.prepared {
+		restErr = h.finalize(restErr)
+	}
+
+	restErr = h.localize(ctx, restErr)
+	restErr = h.selectFormat(ctx, restErr)
+	restErr = h.assignErrorID(ctx, err, restErr)
+	restErr = h.addDebugInfo(ctx, err, restErr)
+
+	if h.reportFn != nil && restErr.originalStatusCode() >= h.reportThreshold {
+		h.reportFn(ctx, err, restErr)
+	}
+
+	for _, fn := range h.metricsHooks {
+		fn(ctx, restErr)
+	}
+	return restErr
+}
+
+// assignErrorID generates an ID for e when it calls for one.
+// The ID is logged along with the original error so that it can be traced back.
+func (h *Handler) assignErrorID(ctx context.Context, err error, e RESTErr) RESTErr {
+	if h.errorIDFn == nil || e.ErrorID != "" {
+		return e
+	}
+
+	when := h.errorIDWhenFn
+	if when == nil {
+		when = isServerErr
+	}
+
+	if !when(e) {
+		return e
+	}
+
+	e.ErrorID = h.errorIDFn()
+	e.json = nil
+
+	h.logger.InfoContext(ctx, "Assigned error ID.", h.errAttr(err), slog.String("error-id", e.ErrorID))
+	return e
+}
+
+// debugInfo is the verbose information about the original error added to debug responses.
+type debugInfo struct {
+	Error  string   `json:"error"`
+	Causes []string `json:"causes,omitempty"`
+}
+
+// jsonMembers are the members of the default JSON format, which extension members must not collide with.
+var jsonMembers = []string{"status-code", "message", "code", "error-id", "severity", "details", "details-truncated", "debug"}
+
+// wireRESTErr is the JSON body of REST errors whose details or debug information
+// are not serialized as by RESTErr alone. Its members shadow the ones of RESTErr.
+type wireRESTErr struct {
+	RESTErr
+	Details          *[]Detail  `json:"details,omitempty"`
+	DetailsTruncated bool       `json:"details-truncated,omitempty"`
+	Debug            *debugInfo `json:"debug,omitempty"`
+}
+
+// stringStatusBody is the JSON body of handlers configured with WithStatusAsString,
+// whose status-code member shadows the numeric one of the REST error.
+type stringStatusBody struct {
+	StatusCode string `json:"status-code"`
+	wireRESTErr
+}
+
+// jsonBody returns the value serialized as the JSON body of e.
+func (h *Handler) jsonBody(e RESTErr) any {
+	if !h.statusAsString && e.debug == nil && !e.detailsTruncated && (len(e.Details) > 0 || h.emptyDetails == EmptyDetailsOmit) {
+		return e
 	}
 
-	h := Handler{
-		logger:          logger.WithGroup("resterr-handler"),
-		errorMap:        sync.Map{},
-		internalErrJSON: internalErrJSON,
+	body := wireRESTErr{RESTErr: e, DetailsTruncated: e.detailsTruncated, Debug: e.debug}
+
+	details := e.Details
+	switch {
+	case len(details) > 0:
+		body.Details = &details
+	case h.emptyDetails == EmptyDetailsNull:
+		details = nil
+		body.Details = &details
+	case h.emptyDetails == EmptyDetailsArray:
+		details = []Detail{}
+		body.Details = &details
 	}
 
-	for _, o := range opts {
-		o(&h)
+	if h.statusAsString {
+		return stringStatusBody{StatusCode: strconv.Itoa(e.StatusCode), wireRESTErr: body}
 	}
+	return body
+}
 
-	for k, e := range errMap {
-		if h.validationFn != nil {
-			if err := h.validationFn(e); err != nil {
-				return nil, fmt.Errorf("validation failed for REST error '%v': %w", e, err)
-			}
-		}
+// addDebugInfo adds the debug information about err to e when ctx holds a truthy debug flag.
+// The pre-marshaled JSON of e is dropped so that the response includes it.
+func (h *Handler) addDebugInfo(ctx context.Context, err error, e RESTErr) RESTErr {
+	if h.debugKey == nil || err == nil || !debugEnabled(ctx.Value(h.debugKey)) {
+		return e
+	}
 
-		res, err := json.Marshal(&e)
-		if err != nil {
-			return nil, fmt.Errorf("could not marshal REST error '%v': %w", e, err)
-		}
-		e.json = res
+	info := debugInfo{Error: err.Error()}
+	for _, c := range unwrapAll(err, h.unwrapFn)[1:] {
+		info.Causes = append(info.Causes, c.Error())
+	}
 
-		h.errorMap.Store(k, e)
+	e.debug = &info
+	e.json = nil
+	return e
+}
+
+// debugEnabled reports whether v is a truthy debug flag.
+func debugEnabled(v any) bool {
+	switch flag := v.(type) {
+	case bool:
+		return flag
+	case string:
+		enabled, _ := strconv.ParseBool(flag)
+		return enabled
+	default:
+		return false
 	}
-	return &h, nil
 }
 
-// Writer defines the interface for writing error data.
-type Writer interface {
-	Write([]byte) (int, error)
-	WriteHeader(statusCode int)
-	Header() http.Header
+// localize replaces the message of e with its translation for the locale in ctx, if any.
+func (h *Handler) localize(ctx context.Context, e RESTErr) RESTErr {
+	if h.localeKey == nil {
+		return e
+	}
+
+	locale, ok := ctx.Value(h.localeKey).(string)
+	if !ok {
+		return e
+	}
+
+	msg, ok := e.Translations[locale]
+	if !ok {
+		return e
+	}
+
+	e.Message = msg
+	e.json = e.localized[locale]
+	return e
 }
 
-// Handle logs the original error and checks for the error in the error -> REST error map
-// provided at initialization. If the error is present in the map, it writes the REST error as JSON.
-// Otherwise, it writes a JSON indicating an internal server error.
-func (h *Handler) Handle(ctx context.Context, w Writer, err error) {
-	w.Header().Set("Content-Type", "application/json")
+// match looks up the REST error for err, which is the internal server error when err is unmapped.
+func (h *Handler) match(ctx context.Context, err error) (RESTErr, resolution) {
+	restErr, res, found := h.find(ctx, err)
+	if !found {
+		return h.internalRESTErr(), res
+	}
+	return restErr, res
+}
+
+// resolution describes how an error was resolved, for the log line of its handling.
+// attr is the attribute of the mapping that matched, such as its domain, if any, and
+// logRESTErr tells whether the line has the resolved REST error, which errors that are
+// REST errors already have as the original error. Errors resolved by an error map are
+// logged with the position of the mapping in the order the mappings are matched in,
+// so that operators can tell why an error matching several mappings resolved to one.
+type resolution struct {
+	msg        string
+	attr       slog.Attr
+	logRESTErr bool
+	indexed    bool
+	index      int
+}
+
+// mapped returns the resolution of an error matching the mapping at index of an error map.
+func mapped(msg string, attr slog.Attr, index int) resolution {
+	return resolution{msg: msg, attr: attr, logRESTErr: true, indexed: true, index: index}
+}
+
+// logResolution logs how err was resolved to restErr, along with the request ID echoed by
+// HandleRequest and the log attributes of restErr, and warns about the deprecated mapping
+// of restErr, if any. The level is the one of the status code of restErr, or of the status
+// it had before being rewritten or overridden when higher, so that rewriting server errors
+// to client errors does not hide them at info level.
+func (h *Handler) logResolution(ctx context.Context, err error, restErr RESTErr, res resolution) {
+	// The attributes are appended to an array on the stack, which is only
+	// outgrown by REST errors with log attributes.
+	var buf [5]slog.Attr
+	attrs := append(buf[:0], h.errAttr(err))
+
+	if res.logRESTErr {
+		attrs = append(attrs, slog.String(h.restErrKey, h.redact(restErr.Error())))
+	}
+
+	if res.attr.Key != "" {
+		attrs = append(attrs, res.attr)
+	}
+
+	if res.indexed {
+		attrs = append(attrs, slog.Int("mapping-index", res.index))
+	}
+
+	if h.requestIDHeader != "" {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			attrs = append(attrs, slog.String("request-id", id))
+		}
+	}
+
+	attrs = append(attrs, restErr.LogAttrs...)
+	h.logger.LogAttrs(ctx, h.logLevel(max(restErr.StatusCode, restErr.originalStatusCode())), res.msg, attrs...)
+
+	if restErr.deprecation != "" {
+		h.logger.WarnContext(ctx, "Handled error has a deprecated mapping.",
+			h.errAttr(err), slog.String("deprecation", restErr.deprecation),
+		)
+	}
+}
+
+// find looks up the REST error for err, without logging it nor calling the onHandle function.
+func (h *Handler) find(ctx context.Context, err error) (RESTErr, resolution, bool) {
+	candidates := h.candidates(err)
 
 	var restErr RESTErr
-	if errors.As(err, &restErr) {
-		h.logger.InfoContext(ctx, "Handling REST error.", slog.String("error", err.Error()))
-		h.write(ctx, w, restErr)
-		return
+	for _, c := range candidates {
+		if errors.As(c, &restErr) {
+			return restErr, resolution{msg: "Handling REST error."}, true
+		}
+	}
+
+	var se *statusError
+	for _, c := range candidates {
+		if errors.As(c, &se) && validStatusCode(se.statusCode) {
+			re := RESTErr{StatusCode: se.statusCode, Message: se.Error()}
+			return re, resolution{msg: "Handling error with status.", logRESTErr: true}, true
+		}
 	}
 
-	var found bool
-	h.errorMap.Range(func(k, v any) bool {
+	if h.domainFn != nil {
+		domain := h.domainFn(err)
+		for i, m := range h.domainMappings[domain] {
+			if isAny(candidates, m.err) {
+				return m.restErr, mapped("Handling domain mapped error.", slog.String("domain", domain), i), true
+			}
+		}
+	}
+
+	var (
+		found  bool
+		result RESTErr
+		index  int
+	)
+
+	h.rangeMappings(func(k any, re RESTErr) bool {
 		keyErr, ok := k.(error)
 		if !ok {
-			h.logger.ErrorContext(ctx, "Failed to convert mapped key to error", slog.String("error", err.Error()))
+			h.logger.ErrorContext(ctx, "Failed to convert mapped key to error", h.errAttr(err))
 			return false
 		}
 
-		if errors.Is(err, keyErr) {
-			re, ok := v.(RESTErr)
-			if !ok {
-				h.logger.ErrorContext(ctx, "Failed to convert mapped value to RESTErr", slog.String("error", err.Error()))
-				return false
-			}
-
+		if isAny(candidates, keyErr) {
 			found = true
-			h.logger.InfoContext(ctx, "Handling mapped error.", slog.String("error", err.Error()), slog.String("rest-error", re.Error()))
-			h.write(ctx, w, re)
-			return true
+			result = re
+			return false
 		}
+		index++
 		return true
 	})
 
 	if found {
-		return
+		return result, mapped("Handling mapped error.", slog.Attr{}, index), true
+	}
+
+	if h.normalizeFn != nil {
+		if re, ok := h.matchString(candidates); ok {
+			return re, resolution{msg: "Handling error matched by message.", logRESTErr: true}, true
+		}
+	}
+
+	for _, fn := range h.fallbackMappers {
+		for _, c := range candidates {
+			if re, ok := fn(c); ok {
+				return h.fillFromStatus(re), resolution{msg: "Handling fallback mapped error.", logRESTErr: true}, true
+			}
+		}
+	}
+
+	if h.codeMapperFn != nil {
+		var coder Coder
+		for _, c := range candidates {
+			if !errors.As(c, &coder) {
+				continue
+			}
+
+			if re, ok := h.codeMapperFn(coder.Code()); ok {
+				return h.fillFromStatus(re), resolution{msg: "Handling code mapped error.", attr: slog.Int("code", coder.Code()), logRESTErr: true}, true
+			}
+		}
+	}
+
+	var sc StatusCoder
+	for _, c := range candidates {
+		if errors.As(c, &sc) && validStatusCode(sc.StatusCode()) {
+			var re RESTErr
+			if fb, ok := h.statusFallbacks[sc.StatusCode()]; ok {
+				re = fb
+				re.StatusCode = sc.StatusCode()
+			} else if cd, ok := h.classDefault(sc.StatusCode()); ok {
+				re = cd
+			} else {
+				re = h.fallbackErr
+				re.StatusCode = sc.StatusCode()
+			}
+			if re.Message == "" {
+				re.Message = http.StatusText(re.StatusCode)
+			}
+			return re, resolution{msg: "Handling status coder error.", logRESTErr: true}, true
+		}
+	}
+
+	return RESTErr{}, resolution{msg: "Handling unmapped error."}, false
+}
+
+// logLevel returns the level errors resolved to statusCode are logged at:
+// error from the threshold set with WithErrorLogThreshold and info below.
+func (h *Handler) logLevel(statusCode int) slog.Level {
+	if statusCode >= h.errLogThreshold {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// classDefault returns the REST error set with WithClientErrorDefault or WithServerErrorDefault
+// for the class of statusCode, with statusCode when it has none.
+func (h *Handler) classDefault(statusCode int) (RESTErr, bool) {
+	var e *RESTErr
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		e = h.serverDefault
+	case statusCode >= http.StatusBadRequest:
+		e = h.clientDefault
+	}
+
+	if e == nil {
+		return RESTErr{}, false
+	}
+
+	re := *e
+	if re.StatusCode == 0 {
+		re.StatusCode = statusCode
+	}
+	return re, true
+}
+
+// fillFromStatus fills the body of e, when it has no message, from the REST error
+// set for its status code with WithInternalErrorForStatus.
+func (h *Handler) fillFromStatus(e RESTErr) RESTErr {
+	fb, ok := h.statusFallbacks[e.StatusCode]
+	if !ok || e.Message != "" {
+		return e
+	}
+
+	e.Message = fb.Message
+	if e.Code == 0 {
+		e.Code = fb.Code
+	}
+	if e.Severity == "" {
+		e.Severity = fb.Severity
+	}
+	if len(e.Details) == 0 {
+		e.Details = fb.Details
+	}
+	e.json = nil
+	return e
+}
+
+// matchString looks for a mapped error whose normalized message equals
+// the normalized message of one of the candidates.
+func (h *Handler) matchString(candidates []error) (RESTErr, bool) {
+	normalized := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		normalized = append(normalized, h.normalizeFn(c.Error()))
+	}
+
+	var (
+		found  bool
+		result RESTErr
+	)
+
+	h.rangeMappings(func(k any, re RESTErr) bool {
+		keyErr, ok := k.(error)
+		if !ok {
+			return true
+		}
+
+		if !slices.Contains(normalized, h.normalizeFn(keyErr.Error())) {
+			return true
+		}
+
+		result, found = re, true
+		return false
+	})
+	return result, found
+}
+
+// maxCandidates bounds the expansion of an error tree, protecting against
+// custom unwrappers that produce cycles.
+const maxCandidates = 256
+
+// candidates expands err into the errors to match against the error map.
+// Without a custom unwrapper, err is the only candidate since errors.Is and errors.As
+// already follow the standard Unwrap methods.
+func (h *Handler) candidates(err error) []error {
+	if h.unwrapFn == nil {
+		return []error{err}
 	}
+	return unwrapAll(err, h.unwrapFn)
+}
+
+// unwrapAll returns err followed by the errors in its tree, in breadth-first order,
+// with the additional errors returned by unwrapFn, if not nil.
+func unwrapAll(err error, unwrapFn func(error) []error) []error {
+	var (
+		result []error
+		queue  = []error{err}
+	)
+
+	for len(queue) > 0 && len(result) < maxCandidates {
+		e := queue[0]
+		queue = queue[1:]
+
+		if e == nil {
+			continue
+		}
+		result = append(result, e)
+
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			queue = append(queue, u.Unwrap())
+		case interface{ Unwrap() []error }:
+			queue = append(queue, u.Unwrap()...)
+		}
+
+		if unwrapFn != nil {
+			queue = append(queue, unwrapFn(e)...)
+		}
+	}
+	return result
+}
+
+// isAny reports whether any of the candidates matches target.
+func isAny(candidates []error, target error) bool {
+	for _, c := range candidates {
+		if errors.Is(c, target) {
+			return true
+		}
+	}
+	return false
+}
 
-	h.logger.ErrorContext(ctx, "Handling unmapped error.", slog.String("error", err.Error()))
-	h.writeInternalErr(ctx, w)
+// isServerErr reports whether e is a server error.
+func isServerErr(e RESTErr) bool {
+	return e.StatusCode >= http.StatusInternalServerError
 }
 
-func (h *Handler) writeInternalErr(ctx context.Context, w Writer) {
-	w.WriteHeader(http.StatusInternalServerError)
+// validStatusCode reports whether code is a valid HTTP status code.
+func validStatusCode(code int) bool {
+	return code >= 100 && code <= 599
+}
+
+// notModified reports whether a conditional GET or HEAD request can be answered with
+// 304 Not Modified for a resource last modified at lastModified.
+func notModified(r *http.Request, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have a one-second resolution.
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+func (h *Handler) writeInternalErr(ctx context.Context, w Writer) RESTErr {
+	h.writeHeader(ctx, w, h.internalErrStatus, h.contentType(RESTErr{}), nil)
 	if _, err := w.Write(h.internalErrJSON); err != nil {
-		h.logger.ErrorContext(ctx, "Failed to write internal JSON error.", slog.String("error", err.Error()))
+		h.logger.ErrorContext(ctx, "Failed to write internal JSON error.", slog.String(h.errKey, err.Error()))
+	}
+	h.flush(w)
+	return h.internalRESTErr()
+}
+
+// internalRESTErr returns the internal server error as written by the handler.
+func (h *Handler) internalRESTErr() RESTErr {
+	e := internalErr.withStatus(h.internalErrStatus)
+	e.json = h.internalErrJSON
+	e.prepared = true
+	return e
+}
+
+// truncateMessage truncates the message of e to the maximum message length set
+// with WithMaxMessageLength.
+func (h *Handler) truncateMessage(e RESTErr) RESTErr {
+	if msg := truncate(e.Message, h.maxMessageLen); msg != e.Message {
+		e.Message = msg
+		e.json = nil
 	}
+	return e
 }
 
-func (h *Handler) write(ctx context.Context, w Writer, e RESTErr) {
-	w.WriteHeader(e.StatusCode)
+// validResponse reports whether payload, the body written for e, passes the response validator
+// set with WithResponseValidator, if any, and logs the payloads that do not.
+func (h *Handler) validResponse(ctx context.Context, e RESTErr, payload []byte) bool {
+	if h.responseValidFn == nil {
+		return true
+	}
+
+	if err := h.responseValidFn(payload); err != nil {
+		h.logger.ErrorContext(ctx, "Invalid REST error response.", slog.String("source-error", e.Error()), slog.String(h.errKey, err.Error()), slog.String("body", string(payload)))
+		return false
+	}
+	return true
+}
+
+// write writes e and returns the REST error that was actually written,
+// which is the internal server error when the write fails.
+func (h *Handler) write(ctx context.Context, w Writer, e RESTErr) RESTErr {
+	h.delay(ctx, e)
+
+	e = h.truncateMessage(e)
 
 	// It's likely that we'll be handling mapped or unmapped errors.
 	// They come with JSON bytes, as opposed to when RESTErr
 	// errors are passed directly to the handler.
-	payload := e.json
+	statusCode, payload := e.StatusCode, e.json
+	if payload == nil {
+		buf, err := h.encode(e)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Failed to marshal error during write", slog.String("source-error", e.Error()), slog.String(h.errKey, err.Error()))
+			return h.writeInternalErr(ctx, w)
+		}
+		defer putBuffer(buf)
+		payload = buf.Bytes()
+	}
 
-	var err error
+	written := e
+	if h.summaryMaxBytes > 0 && len(payload) > h.summaryMaxBytes && len(e.Details) > 0 {
+		written = summarize(e)
 
-	if e.json == nil {
-		payload, err = json.Marshal(e)
+		b, err := h.marshal(written)
 		if err != nil {
-			h.logger.ErrorContext(ctx, "Failed to marshal error during write", slog.String("source-error", e.Error()), slog.String("error", err.Error()))
-			h.writeInternalErr(ctx, w)
-			return
+			h.logger.ErrorContext(ctx, "Failed to marshal error summary during write", slog.String("source-error", e.Error()), slog.String(h.errKey, err.Error()))
+			return h.writeInternalErr(ctx, w)
 		}
+		payload = b
+	}
+
+	if !h.validResponse(ctx, e, payload) {
+		return h.writeInternalErr(ctx, w)
+	}
+
+	// The number of details lets clients and dashboards gauge bulk errors without parsing the body.
+	// It is the number of details in the body, so summaries have none.
+	if n := len(written.Details); n > 0 {
+		w.Header().Set("X-Error-Count", strconv.Itoa(n))
 	}
 
+	h.writeHeader(ctx, w, statusCode, h.contentType(e), e.Headers)
+
 	if _, err := w.Write(payload); err != nil {
-		h.logger.ErrorContext(ctx, "Failed to write JSON error.", slog.String("source-error", e.Error()), slog.String("error", err.Error()))
-		h.writeInternalErr(ctx, w)
+		h.logger.ErrorContext(ctx, "Failed to write JSON error.", slog.String("source-error", e.Error()), slog.String(h.errKey, err.Error()))
+		return h.writeInternalErr(ctx, w)
+	}
+	h.flush(w)
+	return written
+}
+
+// delay waits for the response delay of e, if any, or until ctx is done.
+func (h *Handler) delay(ctx context.Context, e RESTErr) {
+	if h.delayFn == nil {
+		return
+	}
+
+	d := h.delayFn(e)
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// summarize returns the summary of e written instead of e when its response is too large:
+// e without its details, whose number is given by the message.
+func summarize(e RESTErr) RESTErr {
+	e.Message = fmt.Sprintf("%d validation errors", len(e.Details))
+	e.Details = nil
+	e.json = nil
+	e.detailsTruncated = true
+	return e
+}
+
+// flush flushes w if auto flushing is enabled and w supports it.
+func (h *Handler) flush(w Writer) {
+	if !h.autoFlush {
+		return
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// marshal serializes e with the configured format, defaulting to JSON.
+// Errors with debug information are always serialized as JSON, and errors with
+// a format selected by the request context with that format.
+func (h *Handler) marshal(e RESTErr) ([]byte, error) {
+	switch h.bodyFormat(e) {
+	case FormatJSON:
+		b, err := json.Marshal(h.jsonBody(e))
+		if err != nil {
+			return nil, err
+		}
+		return appendExtension(b, e.Extension, jsonMembers, "REST error")
+	case FormatProblemDetails:
+		return h.marshalProblem(e)
+	default:
+		return h.marshalFn(e)
+	}
+}
+
+// jsonFormat reports whether e is serialized with the default JSON format.
+func (h *Handler) jsonFormat(e RESTErr) bool {
+	return h.bodyFormat(e) == FormatJSON
+}
+
+// bufferPool holds the buffers REST errors without pre-marshaled JSON are encoded into.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBuffer is the capacity over which buffers are not returned to the pool,
+// so that a single large response does not keep its memory around.
+const maxPooledBuffer = 64 << 10
+
+// encode serializes e like marshal into a buffer from the pool,
+// which must be returned with putBuffer once written.
+func (h *Handler) encode(e RESTErr) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	// Extension members are appended to the marshaled object.
+	if !h.jsonFormat(e) || len(e.Extension) > 0 {
+		b, err := h.marshal(e)
+		if err != nil {
+			putBuffer(buf)
+			return nil, err
+		}
+		buf.Write(b)
+		return buf, nil
+	}
+
+	if err := json.NewEncoder(buf).Encode(h.jsonBody(e)); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+
+	// The encoder terminates values with a newline, which json.Marshal does not.
+	buf.Truncate(buf.Len() - 1)
+	return buf, nil
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// response is like RESTErr.Response but uses the configured format
+// for errors without pre-marshaled JSON.
+func (h *Handler) response(e RESTErr) (int, []byte, error) {
+	if e.json != nil {
+		return e.StatusCode, e.json, nil
+	}
+
+	b, err := h.marshal(e)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not marshal REST error: %w", err)
+	}
+	return e.StatusCode, b, nil
+}
+
+// writeHeader sets the headers common to all error responses, followed by the headers
+// of the error being written, and writes the status code.
+// Headers are only set here so that no work is done for responses that are not written.
+func (h *Handler) writeHeader(ctx context.Context, w Writer, statusCode int, contentType string, headers http.Header) {
+	w.Header().Set("Content-Type", contentType)
+
+	if h.noStore {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
+	if tp, ok := h.traceparent(ctx); ok {
+		w.Header().Set("Traceparent", tp)
+	}
+
+	if !h.sunset.IsZero() {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", h.sunset.UTC().Format(http.TimeFormat))
+	}
+
+	custom := make(http.Header, len(headers)+1)
+	if v, ok := h.retryAfter[statusCode]; ok {
+		custom.Set("Retry-After", v)
+	}
+	maps.Copy(custom, headers)
+
+	keys := make([]string, 0, len(custom))
+	for k := range custom {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	if h.maxHeaders > 0 && len(keys) > h.maxHeaders {
+		h.logger.WarnContext(ctx, "Dropping custom headers over the limit.",
+			slog.Int("limit", h.maxHeaders), slog.Any("dropped", keys[h.maxHeaders:]),
+		)
+		keys = keys[:h.maxHeaders]
+	}
+
+	for _, k := range keys {
+		w.Header()[k] = slices.Clone(custom[k])
+	}
+	w.WriteHeader(statusCode)
+}
+
+// finalize applies the transformations of REST errors that do not depend on the request.
+// It runs once for errors from the error map, before they are pre-marshaled.
+func (h *Handler) finalize(e RESTErr) RESTErr {
+	e = h.rewriteStatus(e)
+
+	if msg, ok := e.MessagesByEnv[h.env]; ok && h.env != "" && msg != e.Message {
+		e.Message = msg
+		e.json = nil
+	}
+
+	if h.defaultSeverity && e.Severity == "" {
+		e.Severity = DefaultSeverity(e.StatusCode)
+	}
+	return e
+}
+
+// rewriteStatus applies the status code rewriter, if any, to e.
+// A rewritten error loses its pre-marshaled JSON since the body must reflect the new status.
+func (h *Handler) rewriteStatus(e RESTErr) RESTErr {
+	if h.statusRewriteFn == nil {
+		return e
+	}
+
+	if code := h.statusRewriteFn(e.StatusCode); code != e.StatusCode {
+		e = e.withStatus(code)
+		e.json = nil
+	}
+	return e
+}
+
+// withStatus returns e with statusCode, keeping the status code e was resolved to
+// before it was first rewritten or overridden.
+func (e RESTErr) withStatus(statusCode int) RESTErr {
+	if e.originalStatus == 0 {
+		e.originalStatus = e.StatusCode
+	}
+	e.StatusCode = statusCode
+	return e
+}
+
+// originalStatusCode returns the status code e was resolved to before it was rewritten or overridden.
+func (e RESTErr) originalStatusCode() int {
+	if e.originalStatus != 0 {
+		return e.originalStatus
 	}
+	return e.StatusCode
 }
@@ -4,11 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -105,6 +112,160 @@ func TestNewHandler(t *testing.T) {
 			assert.ErrorIs(t, err, tc.expectedErr)
 		}
 	})
+
+	t.Run("with nil error key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewHandler(logger, map[error]RESTErr{nil: {StatusCode: http.StatusTeapot, Message: "teapot"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+	errQux := errors.New("qux err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: errFoo.Error()},
+		errBar: {StatusCode: http.StatusNotFound, Message: errBar.Error()},
+		errQux: {StatusCode: http.StatusConflict, Message: errQux.Error()},
+	}
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		expectedCodes []int
+	}{
+		{
+			name:          "error map",
+			expectedCodes: []int{http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+		},
+		{
+			name:          "with standard errors",
+			givenOpts:     []Option{WithStandardErrors()},
+			expectedCodes: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+		},
+		{
+			name: "with rewritten status codes",
+			givenOpts: []Option{WithStatusCodeRewriter(func(statusCode int) int {
+				if statusCode == http.StatusInternalServerError {
+					return http.StatusServiceUnavailable
+				}
+				return statusCode
+			})},
+			expectedCodes: []int{http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable},
+		},
+		{
+			name: "with domain router",
+			givenOpts: []Option{WithDomainRouter(func(err error) string { return "billing" }, map[string]map[error]RESTErr{
+				"billing": {errFoo: {StatusCode: http.StatusPaymentRequired, Message: "payment required"}},
+			})},
+			expectedCodes: []int{http.StatusPaymentRequired, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+		},
+		{
+			name: "with routes",
+			givenOpts: []Option{WithRoutes(Route{
+				Path:     "/orders/*",
+				ErrorMap: map[error]RESTErr{errFoo: {StatusCode: http.StatusGone, Message: "order deleted"}},
+			})},
+			expectedCodes: []int{http.StatusNotFound, http.StatusConflict, http.StatusGone, http.StatusInternalServerError},
+		},
+		{
+			name: "with variants",
+			givenOpts: []Option{WithVariants(func(*http.Request) string { return "rich" }, map[error]map[string]RESTErr{
+				errFoo: {"rich": {StatusCode: http.StatusUnprocessableEntity, Message: "invalid form"}},
+			})},
+			expectedCodes: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedCodes, handler.StatusCodes())
+		})
+	}
+}
+
+func TestNewHandlerWithEmptyMap(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	testCases := []struct {
+		name          string
+		givenErrMap   map[error]RESTErr
+		givenOpts     []Option
+		expectedErr   bool
+		expectedWarns bool
+	}{
+		{
+			name: "allowed by default",
+		},
+		{
+			name:          "warn on empty map",
+			givenOpts:     []Option{WithWarnOnEmptyMap()},
+			expectedWarns: true,
+		},
+		{
+			name:        "require non empty map",
+			givenOpts:   []Option{WithRequireNonEmptyMap()},
+			expectedErr: true,
+		},
+		{
+			name:        "standard errors do not count",
+			givenOpts:   []Option{WithRequireNonEmptyMap(), WithStandardErrors()},
+			expectedErr: true,
+		},
+		{
+			name:        "non empty map",
+			givenErrMap: map[error]RESTErr{errFoo: {StatusCode: http.StatusNotFound, Message: errFoo.Error()}},
+			givenOpts:   []Option{WithRequireNonEmptyMap(), WithWarnOnEmptyMap()},
+		},
+		{
+			name: "domain mappings",
+			givenOpts: []Option{
+				WithRequireNonEmptyMap(),
+				WithDomainRouter(func(error) string { return "billing" }, map[string]map[error]RESTErr{
+					"billing": {errFoo: {StatusCode: http.StatusPaymentRequired, Message: errFoo.Error()}},
+				}),
+			},
+		},
+		{
+			name:      "auto registered mappings",
+			givenOpts: []Option{WithRequireNonEmptyMap(), AutoRegister(errors.New("user not found"))},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			_, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), tc.givenErrMap, tc.givenOpts...)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedWarns, strings.Contains(logData.String(), "Error map is empty"))
+		})
+	}
 }
 
 func TestHandle(t *testing.T) {
@@ -148,13 +309,13 @@ func TestHandle(t *testing.T) {
 		{
 			name: "RESTErr sent directly to handler",
 			givenErr: RESTErr{
-				StatusCode: http.StatusEarlyHints,
+				StatusCode: http.StatusUnprocessableEntity,
 				Message:    "message",
 			},
-			expectedStatusCode: http.StatusEarlyHints,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 			expectedLogLvl:     "INFO",
 			expectedErr: RESTErr{
-				StatusCode: http.StatusEarlyHints,
+				StatusCode: http.StatusUnprocessableEntity,
 				Message:    "message",
 			},
 		},
@@ -196,59 +357,215 @@ func TestHandle(t *testing.T) {
 	}
 }
 
-func TestWriteInternalErr(t *testing.T) {
+type multiCauseErr struct {
+	causes []error
+}
+
+func (e multiCauseErr) Error() string {
+	return "multi cause error"
+}
+
+func (e multiCauseErr) Causes() []error {
+	return e.causes
+}
+
+func TestHandleWithStandardErrors(t *testing.T) {
 	t.Parallel()
 
-	handler, err := NewHandler(slog.Default(), map[error]RESTErr{})
-	require.NoError(t, err)
+	errorMap := map[error]RESTErr{
+		io.EOF: {
+			StatusCode: http.StatusUnprocessableEntity,
+			Message:    "request body is required",
+		},
+	}
 
-	payload := httptest.NewRecorder()
+	testCases := []struct {
+		name        string
+		givenOpts   []Option
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			name:        "without option",
+			givenErr:    fmt.Errorf("could not decode body: %w", io.ErrUnexpectedEOF),
+			expectedErr: internalErr,
+		},
+		{
+			name:      "truncated body",
+			givenOpts: []Option{WithStandardErrors()},
+			givenErr:  fmt.Errorf("could not decode body: %w", io.ErrUnexpectedEOF),
+			expectedErr: RESTErr{
+				StatusCode: http.StatusBadRequest,
+				Message:    "malformed request body",
+			},
+		},
+		{
+			name:        "user mapping takes precedence",
+			givenOpts:   []Option{WithStandardErrors()},
+			givenErr:    fmt.Errorf("could not decode body: %w", io.EOF),
+			expectedErr: errorMap[io.EOF],
+		},
+	}
 
-	handler.writeInternalErr(context.TODO(), payload)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-	assert.Equal(t, http.StatusInternalServerError, payload.Result().StatusCode)
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
 
-	var result RESTErr
-	require.NoError(t, json.NewDecoder(payload.Result().Body).Decode(&result))
+			writer := httptest.NewRecorder()
 
-	assert.Equal(t, internalErr, result)
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedErr.StatusCode, writer.Code)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedErr, result)
+		})
+	}
 }
 
-func TestWrite(t *testing.T) {
+type statusCoderErr struct {
+	statusCode int
+}
+
+func (e statusCoderErr) Error() string {
+	return fmt.Sprintf("status coder error %d", e.statusCode)
+}
+
+func (e statusCoderErr) StatusCode() int {
+	return e.statusCode
+}
+
+func TestHandleWithInternalErrorForStatus(t *testing.T) {
 	t.Parallel()
 
-	errWithJSON := RESTErr{
-		StatusCode: 123,
-		Message:    "foo-message",
+	errUpstream := errors.New("upstream err")
+
+	internalErrs := map[int]RESTErr{
+		http.StatusBadGateway:         {Message: "bad gateway"},
+		http.StatusServiceUnavailable: {Message: "service unavailable", Code: 7},
 	}
 
-	b, err := json.Marshal(errWithJSON)
-	require.NoError(t, err)
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "status coder error",
+			givenErr:     statusCoderErr{statusCode: http.StatusBadGateway},
+			expectedBody: `{"status-code":502,"message":"bad gateway"}`,
+		},
+		{
+			name:         "status coder error without internal error for status",
+			givenErr:     statusCoderErr{statusCode: http.StatusGatewayTimeout},
+			expectedBody: `{"status-code":504,"message":"Gateway Timeout"}`,
+		},
+		{
+			name:         "status coder error over fallback REST error",
+			givenOpts:    []Option{WithFallbackRESTErr(RESTErr{Message: "fallback"})},
+			givenErr:     statusCoderErr{statusCode: http.StatusServiceUnavailable},
+			expectedBody: `{"status-code":503,"message":"service unavailable","code":7}`,
+		},
+		{
+			name: "fallback mapper without message",
+			givenOpts: []Option{WithFallbackMapper(func(err error) (RESTErr, bool) {
+				return RESTErr{StatusCode: http.StatusBadGateway}, errors.Is(err, errUpstream)
+			})},
+			givenErr:     errUpstream,
+			expectedBody: `{"status-code":502,"message":"bad gateway"}`,
+		},
+		{
+			name: "fallback mapper with message",
+			givenOpts: []Option{WithFallbackMapper(func(err error) (RESTErr, bool) {
+				return RESTErr{StatusCode: http.StatusBadGateway, Message: "payments down"}, errors.Is(err, errUpstream)
+			})},
+			givenErr:     errUpstream,
+			expectedBody: `{"status-code":502,"message":"payments down"}`,
+		},
+		{
+			name:         "unknown error",
+			givenErr:     errors.New("foo err"),
+			expectedBody: `{"status-code":500,"message":"something went wrong"}`,
+		},
+	}
 
-	errWithJSON.json = b
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-	errWithoutJSON := RESTErr{
-		StatusCode: 456,
-		Message:    "bar-message",
+			opts := append([]Option{WithInternalErrorForStatus(internalErrs)}, tc.givenOpts...)
+
+			handler, err := NewHandler(logger, map[error]RESTErr{}, opts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
 	}
+}
 
-	bb, err := json.Marshal(errWithoutJSON)
-	require.NoError(t, err)
+func TestHandleWithErrorClassDefaults(t *testing.T) {
+	t.Parallel()
+
+	errFoo := statusCoderErr{statusCode: http.StatusConflict}
+
+	classOpts := []Option{
+		WithClientErrorDefault(RESTErr{StatusCode: http.StatusBadRequest, Message: "invalid request"}),
+		WithServerErrorDefault(RESTErr{Message: "temporarily unavailable"}),
+	}
 
 	testCases := []struct {
 		name         string
-		givenErr     RESTErr
-		expectedJSON []byte
+		givenMap     map[error]RESTErr
+		givenOpts    []Option
+		givenErr     error
+		expectedBody string
 	}{
 		{
-			name:         "error contain pre-compiled JSON",
-			givenErr:     errWithJSON,
-			expectedJSON: b,
+			name:         "client error",
+			givenErr:     statusCoderErr{statusCode: http.StatusUnprocessableEntity},
+			expectedBody: `{"status-code":400,"message":"invalid request"}`,
 		},
 		{
-			name:         "error does not contain pre-compiled JSON",
-			givenErr:     errWithoutJSON,
-			expectedJSON: bb,
+			name:         "server error keeps the signaled status code",
+			givenErr:     statusCoderErr{statusCode: http.StatusServiceUnavailable},
+			expectedBody: `{"status-code":503,"message":"temporarily unavailable"}`,
+		},
+		{
+			name: "exact mapping",
+			givenMap: map[error]RESTErr{
+				errFoo: {StatusCode: http.StatusConflict, Message: "already exists"},
+			},
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":409,"message":"already exists"}`,
+		},
+		{
+			name: "internal error for status",
+			givenOpts: []Option{WithInternalErrorForStatus(map[int]RESTErr{
+				http.StatusBadGateway: {Message: "bad gateway"},
+			})},
+			givenErr:     statusCoderErr{statusCode: http.StatusBadGateway},
+			expectedBody: `{"status-code":502,"message":"bad gateway"}`,
+		},
+		{
+			name:         "over fallback REST error",
+			givenOpts:    []Option{WithFallbackRESTErr(RESTErr{Message: "fallback"})},
+			givenErr:     statusCoderErr{statusCode: http.StatusNotFound},
+			expectedBody: `{"status-code":400,"message":"invalid request"}`,
+		},
+		{
+			name:         "unknown error",
+			givenErr:     errors.New("foo err"),
+			expectedBody: `{"status-code":500,"message":"something went wrong"}`,
 		},
 	}
 
@@ -256,12 +573,4151 @@ func TestWrite(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			var (
-				writeHeaderCalled bool
-				writeCalled       bool
-			)
+			handler, err := NewHandler(logger, tc.givenMap, append(classOpts, tc.givenOpts...)...)
+			require.NoError(t, err)
 
-			w := mockLogWriter{
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithStatusCoder(t *testing.T) {
+	t.Parallel()
+
+	errFoo := statusCoderErr{statusCode: http.StatusConflict}
+
+	testCases := []struct {
+		name        string
+		givenMap    map[error]RESTErr
+		givenOpts   []Option
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			name:     "default fallback",
+			givenErr: fmt.Errorf("wrapped: %w", errFoo),
+			expectedErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    http.StatusText(http.StatusConflict),
+			},
+		},
+		{
+			name: "custom fallback",
+			givenOpts: []Option{WithFallbackRESTErr(RESTErr{
+				Message: "request could not be processed",
+			})},
+			givenErr: errFoo,
+			expectedErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "request could not be processed",
+			},
+		},
+		{
+			name: "mapping takes precedence",
+			givenMap: map[error]RESTErr{
+				errFoo: {
+					StatusCode: http.StatusTeapot,
+					Message:    "teapot",
+				},
+			},
+			givenErr: errFoo,
+			expectedErr: RESTErr{
+				StatusCode: http.StatusTeapot,
+				Message:    "teapot",
+			},
+		},
+		{
+			name:        "invalid status code",
+			givenErr:    statusCoderErr{statusCode: 1000},
+			expectedErr: internalErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, tc.givenMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedErr.StatusCode, writer.Code)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedErr, result)
+		})
+	}
+}
+
+func TestHandleWithErrorStringNormalizer(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("user not found")
+
+	errorMap := map[error]RESTErr{
+		errNotFound: {
+			StatusCode: http.StatusNotFound,
+			Message:    errNotFound.Error(),
+		},
+	}
+
+	stripID := func(s string) string {
+		before, _, _ := strings.Cut(s, ": id=")
+		return before
+	}
+
+	testCases := []struct {
+		name               string
+		givenOpts          []Option
+		givenErr           error
+		expectedStatusCode int
+	}{
+		{
+			name:               "without normalizer",
+			givenErr:           errors.New("user not found: id=42"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "with normalizer",
+			givenOpts:          []Option{WithErrorStringNormalizer(stripID)},
+			givenErr:           errors.New("user not found: id=42"),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "with normalizer and wrapped match",
+			givenOpts:          []Option{WithErrorStringNormalizer(stripID)},
+			givenErr:           fmt.Errorf("get user: %w", errNotFound),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "with normalizer and no match",
+			givenOpts:          []Option{WithErrorStringNormalizer(stripID)},
+			givenErr:           errors.New("order not found: id=42"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+		})
+	}
+}
+
+func TestHandleWithNoStore(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name                 string
+		givenOpts            []Option
+		givenErr             error
+		expectedCacheControl string
+	}{
+		{
+			name:     "without option",
+			givenErr: errFoo,
+		},
+		{
+			name:                 "mapped error",
+			givenOpts:            []Option{WithNoStore()},
+			givenErr:             errFoo,
+			expectedCacheControl: "no-store",
+		},
+		{
+			name:                 "unmapped error",
+			givenOpts:            []Option{WithNoStore()},
+			givenErr:             errors.New("qux err"),
+			expectedCacheControl: "no-store",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedCacheControl, writer.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestHandleWithMinimalFormat(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		givenKey           string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "mapped error",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusTeapot,
+			expectedBody:       `{"m":"foo err"}`,
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("qux err"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"m":"something went wrong"}`,
+		},
+		{
+			name:     "RESTErr sent directly to handler with custom key",
+			givenKey: "e",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"e":"conflict"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, WithMinimalFormat(tc.givenKey))
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+type domainErr struct {
+	domain string
+	err    error
+}
+
+func (e domainErr) Error() string {
+	return e.domain + ": " + e.err.Error()
+}
+
+func (e domainErr) Unwrap() error {
+	return e.err
+}
+
+func TestHandleWithDomainRouter(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("not found")
+	errInvalid := errors.New("invalid")
+
+	errorMap := map[error]RESTErr{
+		errNotFound: {
+			StatusCode: http.StatusNotFound,
+			Message:    "resource not found",
+		},
+		errInvalid: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "invalid request",
+		},
+	}
+
+	domains := map[string]map[error]RESTErr{
+		"billing": {
+			errNotFound: {
+				StatusCode: http.StatusNotFound,
+				Message:    "invoice not found",
+			},
+		},
+	}
+
+	domainFn := func(err error) string {
+		var de domainErr
+		if errors.As(err, &de) {
+			return de.domain
+		}
+		return ""
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithDomainRouter(domainFn, domains))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			name:        "domain mapped error",
+			givenErr:    fmt.Errorf("get invoice: %w", domainErr{domain: "billing", err: errNotFound}),
+			expectedErr: domains["billing"][errNotFound],
+		},
+		{
+			name:        "domain without matching error",
+			givenErr:    domainErr{domain: "billing", err: errInvalid},
+			expectedErr: errorMap[errInvalid],
+		},
+		{
+			name:        "unknown domain",
+			givenErr:    domainErr{domain: "shipping", err: errNotFound},
+			expectedErr: errorMap[errNotFound],
+		},
+		{
+			name:        "error without domain",
+			givenErr:    errNotFound,
+			expectedErr: errorMap[errNotFound],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedErr.StatusCode, writer.Code)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedErr, result)
+		})
+	}
+
+	t.Run("validates domain errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewHandler(logger, map[error]RESTErr{},
+			WithDomainRouter(domainFn, domains),
+			WithValidationFn(func(restErr RESTErr) error { return assert.AnError }),
+		)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+type localeCtxKey struct{}
+
+type debugCtxKey struct{}
+
+func TestHandleWithDebugFromContext(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithDebugFromContext(debugCtxKey{}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenFlag    any
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "mapped error in debug mode",
+			givenFlag:    true,
+			givenErr:     fmt.Errorf("find user: %w", errFoo),
+			expectedBody: `{"status-code":404,"message":"not found","debug":{"error":"find user: foo err","causes":["foo err"]}}`,
+		},
+		{
+			name:         "unmapped error in debug mode",
+			givenFlag:    "true",
+			givenErr:     errors.New("connection refused"),
+			expectedBody: `{"status-code":500,"message":"something went wrong","debug":{"error":"connection refused"}}`,
+		},
+		{
+			name:         "mapped error without debug flag",
+			givenErr:     fmt.Errorf("find user: %w", errFoo),
+			expectedBody: `{"status-code":404,"message":"not found"}`,
+		},
+		{
+			name:         "mapped error with false debug flag",
+			givenFlag:    false,
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":404,"message":"not found"}`,
+		},
+		{
+			name:         "mapped error with invalid debug flag",
+			givenFlag:    1,
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":404,"message":"not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.TODO()
+			if tc.givenFlag != nil {
+				ctx = context.WithValue(ctx, debugCtxKey{}, tc.givenFlag)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(ctx, writer, tc.givenErr)
+
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithEnvironment(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusConflict,
+			Message:    "conflict",
+			MessagesByEnv: map[string]string{
+				"staging": "conflict: unique index users_email_key",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		givenErr        error
+		expectedMessage string
+	}{
+		{
+			name:            "without environment",
+			givenErr:        errFoo,
+			expectedMessage: "conflict",
+		},
+		{
+			name:            "environment with message",
+			givenOpts:       []Option{WithEnvironment("staging")},
+			givenErr:        errFoo,
+			expectedMessage: "conflict: unique index users_email_key",
+		},
+		{
+			name:            "environment without message",
+			givenOpts:       []Option{WithEnvironment("production")},
+			givenErr:        errFoo,
+			expectedMessage: "conflict",
+		},
+		{
+			name:      "RESTErr sent directly to handler",
+			givenOpts: []Option{WithEnvironment("staging")},
+			givenErr: RESTErr{
+				StatusCode:    http.StatusGone,
+				Message:       "gone",
+				MessagesByEnv: map[string]string{"staging": "gone: deleted by job 42"},
+			},
+			expectedMessage: "gone: deleted by job 42",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			var e RESTErr
+			require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &e))
+
+			assert.Equal(t, tc.expectedMessage, e.Message)
+		})
+	}
+}
+
+func TestHandleWithLocaleFromContext(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+			Translations: map[string]string{
+				"pt-BR": "não encontrado",
+			},
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithLocaleFromContext(localeCtxKey{}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		givenLocale     any
+		givenErr        error
+		expectedMessage string
+	}{
+		{
+			name:            "mapped error with translation",
+			givenLocale:     "pt-BR",
+			givenErr:        errFoo,
+			expectedMessage: "não encontrado",
+		},
+		{
+			name:            "mapped error without translation",
+			givenLocale:     "fr-FR",
+			givenErr:        errFoo,
+			expectedMessage: "not found",
+		},
+		{
+			name:            "mapped error without locale",
+			givenErr:        errFoo,
+			expectedMessage: "not found",
+		},
+		{
+			name:            "mapped error with invalid locale",
+			givenLocale:     42,
+			givenErr:        errFoo,
+			expectedMessage: "not found",
+		},
+		{
+			name:        "RESTErr sent directly to handler",
+			givenLocale: "pt-BR",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+				Translations: map[string]string{
+					"pt-BR": "conflito",
+				},
+			},
+			expectedMessage: "conflito",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.TODO()
+			if tc.givenLocale != nil {
+				ctx = context.WithValue(ctx, localeCtxKey{}, tc.givenLocale)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(ctx, writer, tc.givenErr)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedMessage, result.Message)
+		})
+	}
+}
+
+func TestHandleWithHeaders(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTooManyRequests,
+			Message:    errFoo.Error(),
+			Headers: http.Header{
+				"Retry-After":   []string{"60"},
+				"Cache-Control": []string{"max-age=60"},
+			},
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithNoStore())
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, errFoo)
+
+	assert.Equal(t, http.StatusTooManyRequests, writer.Code)
+	assert.Equal(t, "60", writer.Header().Get("Retry-After"))
+	assert.Equal(t, "max-age=60", writer.Header().Get("Cache-Control"))
+}
+
+func TestHandleWithFallbackMapper(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	mapper := func(err error) (RESTErr, bool) {
+		if strings.HasPrefix(err.Error(), "upstream:") {
+			return RESTErr{StatusCode: http.StatusBadGateway, Message: "bad gateway"}, true
+		}
+		return RESTErr{}, false
+	}
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithFallbackMapper(mapper))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+	}{
+		{
+			name:               "mapping takes precedence",
+			givenErr:           fmt.Errorf("upstream: %w", errFoo),
+			expectedStatusCode: http.StatusTeapot,
+		},
+		{
+			name:               "fallback mapped error",
+			givenErr:           errors.New("upstream: timeout"),
+			expectedStatusCode: http.StatusBadGateway,
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("qux err"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+		})
+	}
+}
+
+func TestHandleWithErrorID(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusForbidden,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusBadGateway,
+			Message:    errBar.Error(),
+		},
+	}
+
+	idFn := func() string { return "id-123" }
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		givenErr        error
+		expectedErrorID string
+	}{
+		{
+			name:      "client error",
+			givenOpts: []Option{WithErrorID(idFn)},
+			givenErr:  errFoo,
+		},
+		{
+			name:            "server error",
+			givenOpts:       []Option{WithErrorID(idFn)},
+			givenErr:        errBar,
+			expectedErrorID: "id-123",
+		},
+		{
+			name:            "unmapped error",
+			givenOpts:       []Option{WithErrorID(idFn)},
+			givenErr:        errors.New("qux err"),
+			expectedErrorID: "id-123",
+		},
+		{
+			name: "client error with predicate",
+			givenOpts: []Option{WithErrorID(idFn), WithErrorIDWhen(func(restErr RESTErr) bool {
+				return restErr.StatusCode == http.StatusForbidden || restErr.StatusCode >= 500
+			})},
+			givenErr:        errFoo,
+			expectedErrorID: "id-123",
+		},
+		{
+			name:      "predicate without generator",
+			givenOpts: []Option{WithErrorIDWhen(func(restErr RESTErr) bool { return true })},
+			givenErr:  errFoo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData string
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					logData += string(p)
+					return len(p), nil
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedErrorID, result.ErrorID)
+
+			if tc.expectedErrorID != "" {
+				assert.Contains(t, logData, "resterr-handler.error-id="+tc.expectedErrorID)
+			}
+		})
+	}
+}
+
+func TestHandleWithDefaultSeverity(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusNotFound,
+			Message:    errBar.Error(),
+			Severity:   SeverityInfo,
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		givenOpts        []Option
+		givenErr         error
+		expectedSeverity string
+	}{
+		{
+			name:     "without option",
+			givenErr: errFoo,
+		},
+		{
+			name:             "mapped error",
+			givenOpts:        []Option{WithDefaultSeverity()},
+			givenErr:         errFoo,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name:             "mapped error with severity",
+			givenOpts:        []Option{WithDefaultSeverity()},
+			givenErr:         errBar,
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "unmapped error",
+			givenOpts:        []Option{WithDefaultSeverity()},
+			givenErr:         errors.New("qux err"),
+			expectedSeverity: SeverityError,
+		},
+		{
+			name:      "RESTErr sent directly to handler",
+			givenOpts: []Option{WithDefaultSeverity()},
+			givenErr: RESTErr{
+				StatusCode: http.StatusBadGateway,
+				Message:    "bad gateway",
+			},
+			expectedSeverity: SeverityError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedSeverity, result.Severity)
+		})
+	}
+}
+
+func TestHandleWithAutoFlush(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		givenErr        error
+		expectedFlushed bool
+	}{
+		{
+			name:     "without option",
+			givenErr: errFoo,
+		},
+		{
+			name:            "mapped error",
+			givenOpts:       []Option{WithAutoFlush()},
+			givenErr:        errFoo,
+			expectedFlushed: true,
+		},
+		{
+			name:            "unmapped error",
+			givenOpts:       []Option{WithAutoFlush()},
+			givenErr:        errors.New("qux err"),
+			expectedFlushed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedFlushed, writer.Flushed)
+		})
+	}
+
+	t.Run("writer without flusher", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, errorMap, WithAutoFlush())
+		require.NoError(t, err)
+
+		w := mockLogWriter{
+			writeHeaderFunc: func(statusCode int) {},
+			writeFunc: func(p []byte) (n int, err error) {
+				return len(p), nil
+			},
+			headerFunc: func() http.Header {
+				return http.Header{}
+			},
+		}
+
+		assert.NotPanics(t, func() {
+			handler.Handle(context.TODO(), &w, errFoo)
+		})
+	})
+}
+
+type coderErr struct {
+	code int
+}
+
+func (e coderErr) Error() string {
+	return fmt.Sprintf("coder error %d", e.code)
+}
+
+func (e coderErr) Code() int {
+	return e.code
+}
+
+func TestHandleWithCodeMapper(t *testing.T) {
+	t.Parallel()
+
+	mapper := func(code int) (RESTErr, bool) {
+		switch code {
+		case 1001:
+			return RESTErr{StatusCode: http.StatusPaymentRequired, Message: "insufficient funds", Code: code}, true
+		case 1002:
+			return RESTErr{StatusCode: http.StatusForbidden, Message: "account locked"}, true
+		}
+		return RESTErr{}, false
+	}
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithCodeMapper(mapper))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			name:        "mapped code carried in body",
+			givenErr:    fmt.Errorf("charge: %w", coderErr{code: 1001}),
+			expectedErr: RESTErr{StatusCode: http.StatusPaymentRequired, Message: "insufficient funds", Code: 1001},
+		},
+		{
+			name:        "mapped code",
+			givenErr:    coderErr{code: 1002},
+			expectedErr: RESTErr{StatusCode: http.StatusForbidden, Message: "account locked"},
+		},
+		{
+			name:        "unmapped code",
+			givenErr:    coderErr{code: 1003},
+			expectedErr: internalErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedErr.StatusCode, writer.Code)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedErr, result)
+		})
+	}
+}
+
+func TestHandleWithDefaultRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTooManyRequests,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusTooManyRequests,
+			Message:    errBar.Error(),
+			Headers:    http.Header{"Retry-After": []string{"5"}},
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap,
+		WithDefaultRetryAfter(http.StatusTooManyRequests, time.Minute),
+		WithDefaultRetryAfter(http.StatusServiceUnavailable, 90*time.Second),
+		WithDefaultRetryAfter(http.StatusBadGateway, -time.Second),
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedRetryAfter string
+	}{
+		{
+			name:               "default for status",
+			givenErr:           errFoo,
+			expectedRetryAfter: "60",
+		},
+		{
+			name:               "REST error header takes precedence",
+			givenErr:           errBar,
+			expectedRetryAfter: "5",
+		},
+		{
+			name:               "RESTErr sent directly to handler",
+			givenErr:           RESTErr{StatusCode: http.StatusServiceUnavailable, Message: "maintenance"},
+			expectedRetryAfter: "90",
+		},
+		{
+			name:     "status without default",
+			givenErr: errors.New("qux err"),
+		},
+		{
+			name:     "negative default",
+			givenErr: RESTErr{StatusCode: http.StatusBadGateway, Message: "upstream failed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedRetryAfter, writer.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandleWithMaxHeaders(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTooManyRequests,
+			Message:    errFoo.Error(),
+			Headers: http.Header{
+				"X-C": []string{"c"},
+				"X-A": []string{"a"},
+				"X-B": []string{"b"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		expectedHeaders []string
+		droppedHeaders  []string
+	}{
+		{
+			name:            "without limit",
+			givenOpts:       []Option{WithDefaultRetryAfter(http.StatusTooManyRequests, time.Second)},
+			expectedHeaders: []string{"Retry-After", "X-A", "X-B", "X-C"},
+		},
+		{
+			name:            "under limit",
+			givenOpts:       []Option{WithMaxHeaders(3)},
+			expectedHeaders: []string{"X-A", "X-B", "X-C"},
+		},
+		{
+			name: "over limit",
+			givenOpts: []Option{
+				WithMaxHeaders(2),
+				WithDefaultRetryAfter(http.StatusTooManyRequests, time.Second),
+			},
+			expectedHeaders: []string{"Retry-After", "X-A"},
+			droppedHeaders:  []string{"X-B", "X-C"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			// Repeated handling must drop the same headers.
+			for i := 0; i < 10; i++ {
+				writer := httptest.NewRecorder()
+
+				handler.Handle(context.TODO(), writer, errFoo)
+
+				for _, k := range tc.expectedHeaders {
+					assert.NotEmpty(t, writer.Header().Get(k), k)
+				}
+
+				for _, k := range tc.droppedHeaders {
+					assert.Empty(t, writer.Header().Get(k), k)
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	var reported int
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	},
+		WithStatusCodeRewriter(func(int) int { return http.StatusGone }),
+		WithErrorReporter(func(context.Context, error, RESTErr) { reported++ }),
+		WithErrorReportThreshold(http.StatusBadRequest),
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		givenErr        error
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:            "mapped error",
+			givenErr:        fmt.Errorf("wrapped: %w", errFoo),
+			expectedStatus:  http.StatusGone,
+			expectedMessage: "not found",
+		},
+		{
+			name:            "direct REST error",
+			givenErr:        RESTErr{StatusCode: http.StatusConflict, Message: "conflict"},
+			expectedStatus:  http.StatusGone,
+			expectedMessage: "conflict",
+		},
+		{
+			name:            "unmapped error",
+			givenErr:        errors.New("bar err"),
+			expectedStatus:  http.StatusGone,
+			expectedMessage: "something went wrong",
+		},
+		{
+			name: "nil error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			observed := handler.Resolve(context.TODO(), tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatus, observed.StatusCode)
+			assert.Equal(t, tc.expectedMessage, observed.Message)
+		})
+	}
+
+	t.Cleanup(func() {
+		assert.Zero(t, reported)
+	})
+}
+
+func TestTryHandle(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedWrote      bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "mapped error",
+			givenErr:           errFoo,
+			expectedWrote:      true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("bar err"),
+			expectedWrote:      true,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "nil error",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			wrote := handler.TryHandle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedWrote, wrote)
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+
+			if !tc.expectedWrote {
+				assert.Empty(t, writer.Body.String())
+				assert.Empty(t, writer.Header())
+			}
+		})
+	}
+}
+
+func TestHandleNilError(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	t.Run("Handle", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, nil)
+
+		assert.False(t, writer.Flushed)
+		assert.Empty(t, writer.Body.String())
+	})
+
+	t.Run("HandleInto", func(t *testing.T) {
+		t.Parallel()
+
+		out := RESTErr{StatusCode: http.StatusTeapot}
+
+		writer := httptest.NewRecorder()
+		handler.HandleInto(context.TODO(), writer, nil, &out)
+
+		assert.Equal(t, http.StatusTeapot, out.StatusCode)
+		assert.Empty(t, writer.Body.String())
+	})
+
+	t.Run("HandleRequest", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.HandleRequest(writer, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+
+		assert.Empty(t, writer.Body.String())
+		assert.Empty(t, writer.Header())
+	})
+}
+
+func TestHandleRequestWithStatusFromHeader(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	}, WithStatusFromHeader("X-Upstream-Status"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenHeader        string
+		expectedStatusCode int
+	}{
+		{
+			name:               "without header",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "client error",
+			givenHeader:        "410",
+			expectedStatusCode: http.StatusGone,
+		},
+		{
+			name:               "server error",
+			givenHeader:        " 503 ",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "success status is ignored",
+			givenHeader:        "200",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "out of range status is ignored",
+			givenHeader:        "600",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non numeric status is ignored",
+			givenHeader:        "gone",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.givenHeader != "" {
+				req.Header.Set("X-Upstream-Status", tc.givenHeader)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, errFoo)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"status-code":%d,"message":"foo err"}`, tc.expectedStatusCode), writer.Body.String())
+		})
+	}
+}
+
+func TestHandleRequestWithStatusFromHeaderAndStatusCodeRewriter(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	},
+		WithStatusFromHeader("X-Upstream-Status"),
+		WithStatusCodeRewriter(func(statusCode int) int {
+			if statusCode >= http.StatusInternalServerError {
+				return http.StatusServiceUnavailable
+			}
+			return statusCode
+		}),
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenHeader        string
+		expectedStatusCode int
+	}{
+		{
+			name:               "rewritten status",
+			givenHeader:        "500",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "status left as is",
+			givenHeader:        "410",
+			expectedStatusCode: http.StatusGone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Upstream-Status", tc.givenHeader)
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, errFoo)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"status-code":%d,"message":"foo err"}`, tc.expectedStatusCode), writer.Body.String())
+		})
+	}
+}
+
+func TestHandleRequestWithStatusFromHeaderHooks(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	testCases := []struct {
+		name             string
+		givenErr         error
+		givenHeader      string
+		expectedStatus   int
+		expectedReported bool
+		expectedLevel    string
+	}{
+		{
+			name:           "server error header",
+			givenErr:       errFoo,
+			givenHeader:    "503",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedLevel:  "level=ERROR",
+		},
+		{
+			name:           "client error header",
+			givenErr:       errFoo,
+			givenHeader:    "410",
+			expectedStatus: http.StatusGone,
+			expectedLevel:  "level=INFO",
+		},
+		{
+			name:             "client error header over server error",
+			givenErr:         errBar,
+			givenHeader:      "410",
+			expectedStatus:   http.StatusGone,
+			expectedReported: true,
+			expectedLevel:    "level=ERROR",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			var (
+				observed []int
+				reported []int
+			)
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{
+				errFoo: {
+					StatusCode: http.StatusNotFound,
+					Message:    errFoo.Error(),
+				},
+				errBar: {
+					StatusCode: http.StatusBadGateway,
+					Message:    errBar.Error(),
+				},
+			},
+				WithStatusFromHeader("X-Upstream-Status"),
+				WithMetricsHook(func(ctx context.Context, restErr RESTErr) {
+					observed = append(observed, restErr.StatusCode)
+				}),
+				WithErrorReporter(func(ctx context.Context, err error, restErr RESTErr) {
+					reported = append(reported, restErr.StatusCode)
+				}),
+			)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Upstream-Status", tc.givenHeader)
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatus, writer.Code)
+			assert.Equal(t, []int{tc.expectedStatus}, observed)
+
+			if tc.expectedReported {
+				assert.Equal(t, []int{tc.expectedStatus}, reported)
+			} else {
+				assert.Empty(t, reported)
+			}
+			assert.Contains(t, logData.String(), tc.expectedLevel)
+		})
+	}
+}
+
+func TestHandleRequestWithEchoRequestID(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	testCases := []struct {
+		name              string
+		givenOpts         []Option
+		givenRequestID    string
+		expectedRequestID string
+		expectedLogLevel  string
+	}{
+		{
+			name:              "request with ID",
+			givenOpts:         []Option{WithEchoRequestID("X-Request-ID")},
+			givenRequestID:    "abc-123",
+			expectedRequestID: "abc-123",
+			expectedLogLevel:  "ERROR",
+		},
+		{
+			name:              "request with ID below the error log threshold",
+			givenOpts:         []Option{WithEchoRequestID("X-Request-ID"), WithErrorLogThreshold(600)},
+			givenRequestID:    "abc-123",
+			expectedRequestID: "abc-123",
+			expectedLogLevel:  "INFO",
+		},
+		{
+			name:      "request without ID",
+			givenOpts: []Option{WithEchoRequestID("X-Request-ID")},
+		},
+		{
+			name: "request without ID with generator",
+			givenOpts: []Option{
+				WithEchoRequestID("X-Request-ID"),
+				WithRequestIDGenerator(func() string { return "generated" }),
+			},
+			expectedRequestID: "generated",
+			expectedLogLevel:  "ERROR",
+		},
+		{
+			name: "request with ID with generator",
+			givenOpts: []Option{
+				WithEchoRequestID("X-Request-ID"),
+				WithRequestIDGenerator(func() string { return "generated" }),
+			},
+			givenRequestID:    "abc-123",
+			expectedRequestID: "abc-123",
+			expectedLogLevel:  "ERROR",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{}, tc.givenOpts...)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.givenRequestID != "" {
+				req.Header.Set("X-Request-ID", tc.givenRequestID)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, errFoo)
+
+			assert.Equal(t, tc.expectedRequestID, writer.Header().Get("X-Request-ID"))
+
+			if tc.expectedRequestID != "" {
+				// The request ID is on the log line of the resolution rather than on a line of its own.
+				var lines []string
+				for _, line := range strings.Split(logData.String(), "\n") {
+					if strings.Contains(line, "resterr-handler.request-id="+tc.expectedRequestID) {
+						lines = append(lines, line)
+					}
+				}
+				require.Len(t, lines, 1)
+				assert.Contains(t, lines[0], `msg="Handling unmapped error."`)
+				assert.Contains(t, lines[0], "level="+tc.expectedLogLevel)
+			} else {
+				assert.NotContains(t, logData.String(), "request-id")
+			}
+		})
+	}
+}
+
+func TestHandleWithStatus(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "unmapped error",
+			givenErr:           WithStatus(errors.New("email already taken"), http.StatusConflict),
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"status-code":409,"message":"email already taken"}`,
+		},
+		{
+			name:               "wrapped",
+			givenErr:           fmt.Errorf("create user: %w", WithStatus(errors.New("email already taken"), http.StatusConflict)),
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"status-code":409,"message":"email already taken"}`,
+		},
+		{
+			name:               "mapped error",
+			givenErr:           WithStatus(errFoo, http.StatusGone),
+			expectedStatusCode: http.StatusGone,
+			expectedBody:       `{"status-code":410,"message":"foo err"}`,
+		},
+		{
+			name:               "invalid status code",
+			givenErr:           WithStatus(errFoo, 1000),
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"status-code":404,"message":"not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, WithStatus(nil, http.StatusConflict))
+	})
+
+	t.Run("unwraps", func(t *testing.T) {
+		t.Parallel()
+
+		assert.ErrorIs(t, WithStatus(errFoo, http.StatusGone), errFoo)
+	})
+}
+
+func TestHandleErrorCountHeader(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusUnprocessableEntity,
+			Message:    "invalid form",
+			Details: []Detail{
+				{Field: "email", Message: "required"},
+				{Field: "name", Message: "required"},
+			},
+		},
+		errBar: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("with details", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, "2", writer.Header().Get("X-Error-Count"))
+	})
+
+	t.Run("without details", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errBar)
+
+		assert.Empty(t, writer.Header().Values("X-Error-Count"))
+	})
+
+	t.Run("aggregated errors", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.HandleErrors(context.TODO(), writer, http.StatusUnprocessableEntity, errFoo, errBar, errors.New("qux err"))
+
+		assert.Equal(t, "4", writer.Header().Get("X-Error-Count"))
+	})
+}
+
+func TestHandleWithSummaryOnLargeResponse(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusUnprocessableEntity,
+			Message:    "invalid form",
+			Details: []Detail{
+				{Field: "email", Message: "required"},
+				{Field: "name", Message: "required"},
+			},
+		},
+	}
+
+	full, err := json.Marshal(errMap[errFoo])
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		givenMax      int
+		expectedBody  string
+		expectedCount string
+	}{
+		{
+			name:          "at the limit",
+			givenMax:      len(full),
+			expectedBody:  string(full),
+			expectedCount: "2",
+		},
+		{
+			name:         "one byte over the limit",
+			givenMax:     len(full) - 1,
+			expectedBody: `{"status-code":422,"message":"2 validation errors","details-truncated":true}`,
+		},
+		{
+			name:          "disabled",
+			expectedBody:  string(full),
+			expectedCount: "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errMap, WithSummaryOnLargeResponse(tc.givenMax))
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, errFoo)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, writer.Code)
+			assert.Equal(t, tc.expectedCount, writer.Header().Get("X-Error-Count"))
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+
+	t.Run("problem details", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, errMap, WithProblemDetails(), WithSummaryOnLargeResponse(1))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.JSONEq(t, `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"2 validation errors","details-truncated":true}`, writer.Body.String())
+		assert.NotContains(t, writer.Header(), "X-Error-Count")
+	})
+
+	t.Run("summary without error count", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, errMap, WithSummaryOnLargeResponse(1))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.JSONEq(t, `{"status-code":422,"message":"2 validation errors","details-truncated":true}`, writer.Body.String())
+		assert.NotContains(t, writer.Header(), "X-Error-Count")
+	})
+}
+
+func TestHandleStatusMessage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                string
+		givenOpts           []Option
+		givenStatus         int
+		givenMessage        string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "status and message",
+			givenStatus:         http.StatusConflict,
+			givenMessage:        "already exists",
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":409,"message":"already exists"}`,
+		},
+		{
+			name:                "empty message",
+			givenStatus:         http.StatusTooManyRequests,
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":429,"message":"Too Many Requests"}`,
+		},
+		{
+			name:                "configured format and hooks",
+			givenOpts:           []Option{WithProblemDetails(), WithDefaultSeverity()},
+			givenStatus:         http.StatusConflict,
+			givenMessage:        "already exists",
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"type":"about:blank","title":"Conflict","status":409,"detail":"already exists","severity":"warning"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, map[error]RESTErr{}, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.HandleStatusMessage(context.TODO(), writer, tc.givenStatus, tc.givenMessage)
+
+			assert.Equal(t, tc.givenStatus, writer.Code)
+			assert.Equal(t, tc.expectedContentType, writer.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithResponseDelay(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "unavailable",
+		},
+	}, WithResponseDelay(func(restErr RESTErr) time.Duration {
+		if restErr.StatusCode == http.StatusServiceUnavailable {
+			return 50 * time.Millisecond
+		}
+		return 0
+	}))
+	require.NoError(t, err)
+
+	t.Run("delayed", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+	})
+
+	t.Run("not delayed", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.Handle(context.TODO(), writer, errors.New("bar err"))
+
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, http.StatusInternalServerError, writer.Code)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		t.Parallel()
+
+		slow, err := NewHandler(logger, map[error]RESTErr{}, WithResponseDelay(func(RESTErr) time.Duration {
+			return time.Hour
+		}))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+
+		writer := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			slow.Handle(ctx, writer, errFoo)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("canceled request blocked on the response delay")
+		}
+		assert.Equal(t, http.StatusInternalServerError, writer.Code)
+	})
+}
+
+func TestHandleDeterministicResolution(t *testing.T) {
+	t.Parallel()
+
+	errAlpha := errors.New("alpha err")
+	errBeta := errors.New("beta err")
+	errGamma := errors.New("gamma err")
+
+	given := errors.Join(errGamma, errBeta, errAlpha)
+
+	t.Run("error map sorted by message", func(t *testing.T) {
+		t.Parallel()
+
+		for range 50 {
+			handler, err := NewHandler(logger, map[error]RESTErr{
+				errGamma: {StatusCode: http.StatusConflict, Message: "gamma"},
+				errBeta:  {StatusCode: http.StatusNotFound, Message: "beta"},
+				errAlpha: {StatusCode: http.StatusBadRequest, Message: "alpha"},
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, "alpha", handler.Resolve(context.TODO(), given).Message)
+		}
+	})
+
+	t.Run("domain map sorted by message", func(t *testing.T) {
+		t.Parallel()
+
+		for range 50 {
+			handler, err := NewHandler(logger, map[error]RESTErr{}, WithDomainRouter(func(error) string { return "billing" }, map[string]map[error]RESTErr{
+				"billing": {
+					errGamma: {StatusCode: http.StatusConflict, Message: "gamma"},
+					errBeta:  {StatusCode: http.StatusNotFound, Message: "beta"},
+					errAlpha: {StatusCode: http.StatusBadRequest, Message: "alpha"},
+				},
+			}))
+			require.NoError(t, err)
+
+			assert.Equal(t, "alpha", handler.Resolve(context.TODO(), given).Message)
+		}
+	})
+
+	t.Run("route map sorted by message", func(t *testing.T) {
+		t.Parallel()
+
+		for range 50 {
+			handler, err := NewHandler(logger, map[error]RESTErr{}, WithRoutes(Route{
+				Path: "/orders/*",
+				ErrorMap: map[error]RESTErr{
+					errGamma: {StatusCode: http.StatusConflict, Message: "gamma"},
+					errBeta:  {StatusCode: http.StatusNotFound, Message: "beta"},
+					errAlpha: {StatusCode: http.StatusBadRequest, Message: "alpha"},
+				},
+			}))
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.HandleRequest(writer, httptest.NewRequest(http.MethodGet, "/orders/7", nil), given)
+
+			assert.JSONEq(t, `{"status-code":400,"message":"alpha"}`, writer.Body.String())
+		}
+	})
+
+	t.Run("variant map sorted by message", func(t *testing.T) {
+		t.Parallel()
+
+		for range 50 {
+			handler, err := NewHandler(logger, map[error]RESTErr{}, WithVariants(func(*http.Request) string { return "rich" }, map[error]map[string]RESTErr{
+				errGamma: {"rich": {StatusCode: http.StatusConflict, Message: "gamma"}},
+				errBeta:  {"rich": {StatusCode: http.StatusNotFound, Message: "beta"}},
+				errAlpha: {"rich": {StatusCode: http.StatusBadRequest, Message: "alpha"}},
+			}))
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.HandleRequest(writer, httptest.NewRequest(http.MethodGet, "/", nil), given)
+
+			assert.JSONEq(t, `{"status-code":400,"message":"alpha"}`, writer.Body.String())
+		}
+	})
+
+	t.Run("same message sorted by type and rest error", func(t *testing.T) {
+		t.Parallel()
+
+		errText := errors.New("dup err")
+		errJoined := errors.Join(errors.New("dup err"))
+		errLow := errors.New("dup err")
+		errHigh := errors.New("dup err")
+
+		for range 50 {
+			handler, err := NewHandler(logger, map[error]RESTErr{
+				errJoined: {StatusCode: http.StatusBadRequest, Message: "joined"},
+				errHigh:   {StatusCode: http.StatusNotFound, Message: "high"},
+				errText:   {StatusCode: http.StatusBadRequest, Message: "text"},
+				errLow:    {StatusCode: http.StatusBadRequest, Message: "low"},
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, "low", handler.Resolve(context.TODO(), errors.Join(errJoined, errHigh, errText, errLow)).Message)
+			assert.Equal(t, "high", handler.Resolve(context.TODO(), errors.Join(errJoined, errHigh)).Message)
+		}
+	})
+
+	t.Run("first registered wins", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{})
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Register(errGamma, RESTErr{StatusCode: http.StatusConflict, Message: "gamma"}))
+		require.NoError(t, handler.Register(errAlpha, RESTErr{StatusCode: http.StatusBadRequest, Message: "alpha"}))
+		require.NoError(t, handler.Register(errBeta, RESTErr{StatusCode: http.StatusNotFound, Message: "beta"}))
+
+		for range 50 {
+			assert.Equal(t, "gamma", handler.Resolve(context.TODO(), given).Message)
+		}
+
+		// Replacing a mapping keeps its place, while registering it again after removing it does not.
+		require.NoError(t, handler.Register(errGamma, RESTErr{StatusCode: http.StatusConflict, Message: "gamma again"}))
+		assert.Equal(t, "gamma again", handler.Resolve(context.TODO(), given).Message)
+
+		handler.Unregister(errGamma)
+		assert.Equal(t, "alpha", handler.Resolve(context.TODO(), given).Message)
+
+		require.NoError(t, handler.Register(errGamma, RESTErr{StatusCode: http.StatusConflict, Message: "gamma"}))
+		assert.Equal(t, "alpha", handler.Resolve(context.TODO(), given).Message)
+	})
+
+	t.Run("restored snapshot keeps the order", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{})
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Register(errBeta, RESTErr{StatusCode: http.StatusNotFound, Message: "beta"}))
+		require.NoError(t, handler.Register(errAlpha, RESTErr{StatusCode: http.StatusBadRequest, Message: "alpha"}))
+
+		state := handler.Snapshot()
+		handler.Reset()
+		handler.Restore(state)
+
+		assert.Equal(t, "beta", handler.Resolve(context.TODO(), given).Message)
+	})
+}
+
+func TestHandleLogsMappingIndex(t *testing.T) {
+	t.Parallel()
+
+	errAlpha := errors.New("alpha err")
+	errBeta := errors.New("beta err")
+	errGamma := errors.New("gamma err")
+
+	errMap := map[error]RESTErr{
+		errAlpha: {StatusCode: http.StatusBadRequest, Message: "alpha"},
+		errBeta:  {StatusCode: http.StatusNotFound, Message: "beta"},
+		errGamma: {StatusCode: http.StatusConflict, Message: "gamma"},
+	}
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenErr      error
+		expectedIndex string
+	}{
+		{
+			name:          "error map",
+			givenErr:      fmt.Errorf("wrapped: %w", errors.Join(errGamma, errBeta)),
+			expectedIndex: "resterr-handler.mapping-index=1",
+		},
+		{
+			name: "domain map",
+			givenOpts: []Option{WithDomainRouter(func(error) string { return "billing" }, map[string]map[error]RESTErr{
+				"billing": errMap,
+			})},
+			givenErr:      fmt.Errorf("wrapped: %w", errGamma),
+			expectedIndex: "resterr-handler.mapping-index=2",
+		},
+		{
+			name:     "REST error",
+			givenErr: RESTErr{StatusCode: http.StatusConflict, Message: "conflict"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			handler.Handle(context.TODO(), httptest.NewRecorder(), tc.givenErr)
+
+			if tc.expectedIndex != "" {
+				assert.Contains(t, logData.String(), tc.expectedIndex)
+			} else {
+				assert.NotContains(t, logData.String(), "mapping-index")
+			}
+		})
+	}
+}
+
+func TestHandleWithOnHandle(t *testing.T) {
+	t.Parallel()
+
+	var called []error
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithOnHandle(func(ctx context.Context, err error) {
+		called = append(called, err)
+	}))
+	require.NoError(t, err)
+
+	givenErr := errors.New("foo err")
+
+	handler.Handle(context.TODO(), httptest.NewRecorder(), givenErr)
+	handler.HandleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), givenErr)
+
+	require.Len(t, called, 2)
+	assert.Equal(t, givenErr, called[0])
+	assert.Equal(t, givenErr, called[1])
+}
+
+func TestHandleWithMetricsHook(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	var observed []int
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	},
+		WithMetricsHook(func(_ context.Context, restErr RESTErr) {
+			observed = append(observed, restErr.StatusCode)
+		}),
+		WithMetricsHook(func(_ context.Context, restErr RESTErr) {
+			observed = append(observed, -restErr.StatusCode)
+		}),
+	)
+	require.NoError(t, err)
+
+	handler.Handle(context.TODO(), httptest.NewRecorder(), errFoo)
+	handler.Handle(context.TODO(), httptest.NewRecorder(), errors.New("bar err"))
+	handler.Handle(context.TODO(), httptest.NewRecorder(), nil)
+
+	assert.Equal(t, []int{404, -404, 500, -500}, observed)
+}
+
+func TestHandleWithErrorReporter(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		givenOpts          []Option
+		givenErr           error
+		expectedStatusCode int
+		expectedReported   bool
+	}{
+		{
+			name:               "server error is reported",
+			givenErr:           errBar,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedReported:   true,
+		},
+		{
+			name:               "client error is not reported",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "client error over threshold is reported",
+			givenOpts:          []Option{WithErrorReportThreshold(http.StatusBadRequest)},
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedReported:   true,
+		},
+		{
+			name:               "server error rewritten to client error is reported",
+			givenOpts:          []Option{WithStatusCodeRewriter(serverErrorsAsUnprocessable)},
+			givenErr:           errBar,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedReported:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				reported    bool
+				reportedErr error
+				reportedRE  RESTErr
+			)
+
+			opts := append(tc.givenOpts, WithErrorReporter(func(ctx context.Context, err error, restErr RESTErr) {
+				reported = true
+				reportedErr = err
+				reportedRE = restErr
+			}))
+
+			handler, err := NewHandler(logger, errorMap, opts...)
+			require.NoError(t, err)
+
+			// Errors are reported even if the response cannot be written.
+			writer := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return 0, errors.New("connection reset")
+				},
+				writeHeaderFunc: func(statusCode int) {},
+				headerFunc:      func() http.Header { return http.Header{} },
+			}
+
+			handler.Handle(context.TODO(), &writer, tc.givenErr)
+
+			require.Equal(t, tc.expectedReported, reported)
+
+			if tc.expectedReported {
+				assert.Equal(t, tc.givenErr, reportedErr)
+				assert.Equal(t, tc.expectedStatusCode, reportedRE.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandleWithExtension(t *testing.T) {
+	t.Parallel()
+
+	errOutOfCredit := errors.New("out of credit")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errOutOfCredit: {
+			StatusCode: http.StatusForbidden,
+			Message:    "out of credit",
+			Extension:  map[string]any{"balance": 30, "accounts": []string{"/account/12345"}},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "mapped error",
+			givenErr:           errOutOfCredit,
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"status-code":403,"message":"out of credit","accounts":["/account/12345"],"balance":30}`,
+		},
+		{
+			name: "REST error with details",
+			givenErr: RESTErr{
+				StatusCode: http.StatusBadRequest,
+				Message:    "invalid form",
+				Details:    []Detail{{Field: "email", Message: "required"}},
+				Extension:  map[string]any{"form": "signup"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status-code":400,"message":"invalid form","details":[{"field":"email","message":"required"}],"form":"signup"}`,
+		},
+		{
+			name: "colliding extension member",
+			givenErr: RESTErr{
+				StatusCode: http.StatusForbidden,
+				Message:    "forbidden",
+				Extension:  map[string]any{"message": "denied"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status-code":500,"message":"something went wrong"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestNewHandlerWithCollidingExtension(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHandler(logger, map[error]RESTErr{
+		errors.New("foo err"): {
+			StatusCode: http.StatusForbidden,
+			Message:    "forbidden",
+			Extension:  map[string]any{"status-code": 403},
+		},
+	})
+
+	assert.ErrorContains(t, err, "extension member 'status-code' collides with a REST error member")
+}
+
+func TestHandleWithDeprecation(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	sunset := time.Date(2027, time.March, 31, 23, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}, WithDeprecation(sunset))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		givenErr error
+	}{
+		{
+			name:     "mapped error",
+			givenErr: errFoo,
+		},
+		{
+			name:     "unmapped error",
+			givenErr: errors.New("bar err"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, "true", writer.Header().Get("Deprecation"))
+			assert.Equal(t, "Wed, 31 Mar 2027 21:00:00 GMT", writer.Header().Get("Sunset"))
+		})
+	}
+
+	t.Run("not deprecated", func(t *testing.T) {
+		t.Parallel()
+
+		other, err := NewHandler(logger, map[error]RESTErr{})
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		other.Handle(context.TODO(), writer, errFoo)
+
+		assert.Empty(t, writer.Header().Values("Deprecation"))
+		assert.Empty(t, writer.Header().Values("Sunset"))
+	})
+}
+
+func TestHandleWithDeprecateMapping(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusGone,
+			Message:    errFoo.Error(),
+			Headers:    http.Header{"X-Foo": []string{"foo"}},
+		},
+		errBar: {
+			StatusCode: http.StatusConflict,
+			Message:    errBar.Error(),
+		},
+	}
+
+	t.Run("deprecated mapping", func(t *testing.T) {
+		t.Parallel()
+
+		var logData strings.Builder
+
+		logWriter := mockLogWriter{
+			writeFunc: func(p []byte) (n int, err error) {
+				return logData.Write(p)
+			},
+		}
+
+		handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap,
+			DeprecateMapping(errFoo, "use the v2 endpoint"),
+		)
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errFoo)
+
+		assert.Equal(t, http.StatusGone, writer.Code)
+		assert.JSONEq(t, `{"status-code":410,"message":"foo err"}`, writer.Body.String())
+		assert.Equal(t, `299 - "use the v2 endpoint"`, writer.Header().Get("Warning"))
+		assert.Equal(t, "foo", writer.Header().Get("X-Foo"))
+		assert.Contains(t, logData.String(), `level=WARN msg="Handled error has a deprecated mapping."`)
+		assert.Contains(t, logData.String(), `resterr-handler.deprecation="use the v2 endpoint"`)
+
+		// The error map given to the handler is left untouched.
+		assert.Empty(t, errorMap[errFoo].Headers.Get("Warning"))
+	})
+
+	t.Run("mapping not deprecated", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, errorMap, DeprecateMapping(errFoo, "use the v2 endpoint"))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errBar)
+
+		assert.Equal(t, http.StatusConflict, writer.Code)
+		assert.Empty(t, writer.Header().Get("Warning"))
+	})
+
+	t.Run("unmapped error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewHandler(logger, errorMap, DeprecateMapping(errors.New("qux err"), "gone"))
+		require.Error(t, err)
+	})
+}
+
+func TestHandleWithResponseValidator(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusNotFound,
+			Message:    "",
+		},
+	}
+
+	validator := func(body []byte) error {
+		var e RESTErr
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+
+		if e.Message == "" {
+			return errors.New("message is required")
+		}
+		return nil
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithResponseValidator(validator))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "valid response",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"status-code":404,"message":"foo err"}`,
+		},
+		{
+			name:               "invalid response",
+			givenErr:           errBar,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status-code":500,"message":"something went wrong"}`,
+		},
+		{
+			name:               "invalid RESTErr sent directly to handler",
+			givenErr:           RESTErr{StatusCode: http.StatusConflict},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status-code":500,"message":"something went wrong"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithStatusAsString(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "mapped error",
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":"404","message":"not found","code":7}`,
+		},
+		{
+			name:         "unmapped error",
+			givenErr:     errors.New("bar err"),
+			expectedBody: `{"status-code":"500","message":"something went wrong"}`,
+		},
+		{
+			name:         "direct REST error with details",
+			givenErr:     RESTErr{StatusCode: http.StatusUnprocessableEntity, Message: "invalid form", Details: []Detail{{Field: "email", Message: "required"}}},
+			expectedBody: `{"status-code":"422","message":"invalid form","details":[{"field":"email","message":"required"}]}`,
+		},
+		{
+			name:         "empty details array",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsArray)},
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":"404","message":"not found","code":7,"details":[]}`,
+		},
+		{
+			name:         "problem details",
+			givenOpts:    []Option{WithProblemDetails()},
+			givenErr:     errFoo,
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","code":7}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, map[error]RESTErr{
+				errFoo: {
+					StatusCode: http.StatusNotFound,
+					Message:    "not found",
+					Code:       7,
+				},
+			}, append([]Option{WithStatusAsString()}, tc.givenOpts...)...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithEmptyDetailsBehavior(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	withDetails := RESTErr{
+		StatusCode: http.StatusUnprocessableEntity,
+		Message:    "invalid form",
+		Details:    []Detail{{Field: "email", Message: "required"}},
+	}
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "omit by default",
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":404,"message":"foo err"}`,
+		},
+		{
+			name:         "null",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsNull)},
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":404,"message":"foo err","details":null}`,
+		},
+		{
+			name:         "empty array",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsArray)},
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":404,"message":"foo err","details":[]}`,
+		},
+		{
+			name:         "empty array for internal error",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsArray)},
+			givenErr:     errors.New("bar err"),
+			expectedBody: `{"status-code":500,"message":"something went wrong","details":[]}`,
+		},
+		{
+			name:         "empty array for RESTErr sent directly to handler",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsArray)},
+			givenErr:     RESTErr{StatusCode: http.StatusConflict, Message: "conflict", Details: []Detail{}},
+			expectedBody: `{"status-code":409,"message":"conflict","details":[]}`,
+		},
+		{
+			name:         "null with details",
+			givenOpts:    []Option{WithEmptyDetailsBehavior(EmptyDetailsNull)},
+			givenErr:     withDetails,
+			expectedBody: `{"status-code":422,"message":"invalid form","details":[{"field":"email","message":"required"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithLogAttrs(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+			LogAttrs:   []slog.Attr{slog.String("subsystem", "billing")},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		givenErr error
+	}{
+		{
+			name:     "mapped error",
+			givenErr: fmt.Errorf("wrapped: %w", errFoo),
+		},
+		{
+			name: "RESTErr sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+				LogAttrs:   []slog.Attr{slog.String("subsystem", "billing")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData string
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					logData = string(p)
+					return len(p), nil
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap)
+			require.NoError(t, err)
+
+			handler.Handle(context.TODO(), httptest.NewRecorder(), tc.givenErr)
+
+			assert.Contains(t, logData, "resterr-handler.subsystem=billing")
+		})
+	}
+}
+
+// serverErrorsAsUnprocessable rewrites server errors to 422 Unprocessable Entity.
+func serverErrorsAsUnprocessable(statusCode int) int {
+	if statusCode >= http.StatusInternalServerError {
+		return http.StatusUnprocessableEntity
+	}
+	return statusCode
+}
+
+func TestHandleWithErrorLogThreshold(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("not found err")
+	errUnavailable := errors.New("unavailable err")
+
+	errorMap := map[error]RESTErr{
+		errNotFound: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+		errUnavailable: {
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "unavailable",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenErr      error
+		expectedLevel string
+	}{
+		{
+			name:          "client error",
+			givenErr:      errNotFound,
+			expectedLevel: "level=INFO",
+		},
+		{
+			name:          "mapped server error",
+			givenErr:      errUnavailable,
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "unmapped error",
+			givenErr:      errors.New("foo err"),
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "status coder error",
+			givenErr:      statusCoderErr{statusCode: http.StatusBadGateway},
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "client error with lower threshold",
+			givenOpts:     []Option{WithErrorLogThreshold(http.StatusBadRequest)},
+			givenErr:      errNotFound,
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "unmapped error with higher threshold",
+			givenOpts:     []Option{WithErrorLogThreshold(600)},
+			givenErr:      errors.New("foo err"),
+			expectedLevel: "level=INFO",
+		},
+		{
+			name:          "unmapped error rewritten to client error",
+			givenOpts:     []Option{WithStatusCodeRewriter(serverErrorsAsUnprocessable)},
+			givenErr:      errors.New("foo err"),
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "mapped server error rewritten to client error",
+			givenOpts:     []Option{WithStatusCodeRewriter(serverErrorsAsUnprocessable)},
+			givenErr:      errUnavailable,
+			expectedLevel: "level=ERROR",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			handler.Handle(context.TODO(), httptest.NewRecorder(), tc.givenErr)
+
+			assert.Contains(t, logData.String(), tc.expectedLevel+" msg=\"Handling")
+		})
+	}
+}
+
+func TestHandleWithLogKeys(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		givenErr     error
+		expectedKeys []string
+	}{
+		{
+			name:         "mapped error",
+			givenErr:     errFoo,
+			expectedKeys: []string{"resterr-handler.err=", "resterr-handler.rest_err="},
+		},
+		{
+			name:         "unmapped error",
+			givenErr:     errors.New("qux err"),
+			expectedKeys: []string{"resterr-handler.err="},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData string
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					logData = string(p)
+					return len(p), nil
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap, WithLogKeys("err", "rest_err"))
+			require.NoError(t, err)
+
+			handler.Handle(context.TODO(), httptest.NewRecorder(), tc.givenErr)
+
+			for _, key := range tc.expectedKeys {
+				assert.Contains(t, logData, key)
+			}
+			assert.NotContains(t, logData, "resterr-handler.error=")
+		})
+	}
+}
+
+func TestHandleWithLogRedactor(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusBadGateway,
+			Message:    "upstream failed",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		givenErr error
+	}{
+		{
+			name:     "mapped error",
+			givenErr: fmt.Errorf(`upstream replied {"auth":"Bearer eyJhbGciOi.eyJzdWIi.SflKxw"}: %w`, errFoo),
+		},
+		{
+			name:     "unmapped error",
+			givenErr: errors.New(`upstream replied {"auth":"Bearer eyJhbGciOi.eyJzdWIi.SflKxw"}`),
+		},
+		{
+			name:     "error with status",
+			givenErr: WithStatus(errors.New(`upstream replied {"auth":"Bearer eyJhbGciOi.eyJzdWIi.SflKxw"}`), http.StatusBadRequest),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap,
+				WithLogRedactor([]*regexp.Regexp{regexp.MustCompile(`Bearer [\w.-]+`)}),
+			)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Contains(t, logData.String(), `upstream replied {\"auth\":\"***\"}`)
+			assert.NotContains(t, logData.String(), "eyJhbGciOi")
+		})
+	}
+}
+
+func TestHandleWithLogKeysOnWriteFailure(t *testing.T) {
+	t.Parallel()
+
+	var logData strings.Builder
+
+	logWriter := mockLogWriter{
+		writeFunc: func(p []byte) (n int, err error) {
+			return logData.Write(p)
+		},
+	}
+
+	handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{}, WithLogKeys("err", "rest_err"))
+	require.NoError(t, err)
+
+	writer := mockLogWriter{
+		writeFunc: func(p []byte) (n int, err error) {
+			return 0, errors.New("connection reset")
+		},
+		writeHeaderFunc: func(statusCode int) {},
+		headerFunc:      func() http.Header { return http.Header{} },
+	}
+
+	handler.Handle(context.TODO(), &writer, RESTErr{StatusCode: http.StatusConflict, Message: "conflict"})
+
+	assert.Contains(t, logData.String(), `msg="Failed to write JSON error." resterr-handler.source-error=`)
+	assert.Contains(t, logData.String(), `resterr-handler.err="connection reset"`)
+	assert.NotContains(t, logData.String(), "resterr-handler.error=")
+}
+
+func TestHandleWithMaxMessageLength(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	var logData strings.Builder
+
+	logWriter := mockLogWriter{
+		writeFunc: func(p []byte) (n int, err error) {
+			return logData.Write(p)
+		},
+	}
+
+	handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "query failed: SELECT * FROM users",
+		},
+	}, WithMaxMessageLength(12))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "mapped error",
+			givenErr:     errFoo,
+			expectedBody: `{"status-code":400,"message":"query faile…"}`,
+		},
+		{
+			name:         "direct REST error",
+			givenErr:     RESTErr{StatusCode: http.StatusConflict, Message: "déjà réservé"},
+			expectedBody: `{"status-code":409,"message":"déjà réservé"}`,
+		},
+		{
+			name:         "direct REST error with multi-byte characters",
+			givenErr:     RESTErr{StatusCode: http.StatusConflict, Message: "créneau déjà réservé"},
+			expectedBody: `{"status-code":409,"message":"créneau déj…"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+
+	t.Run("logged original error", func(t *testing.T) {
+		handler.Handle(context.TODO(), httptest.NewRecorder(), errors.New("could not run SELECT * FROM users"))
+
+		assert.Contains(t, logData.String(), "error=\"could not r…\"")
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    string
+		givenN   int
+		expected string
+	}{
+		{
+			name:     "shorter",
+			given:    "foo",
+			givenN:   5,
+			expected: "foo",
+		},
+		{
+			name:     "at the limit",
+			given:    "fooba",
+			givenN:   5,
+			expected: "fooba",
+		},
+		{
+			name:     "longer",
+			given:    "foobar",
+			givenN:   5,
+			expected: "foob…",
+		},
+		{
+			name:     "multi-byte characters",
+			given:    "ééééé",
+			givenN:   3,
+			expected: "éé…",
+		},
+		{
+			name:     "disabled",
+			given:    "foobar",
+			expected: "foobar",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, truncate(tc.given, tc.givenN))
+		})
+	}
+}
+
+func TestHandleWithUnwrapper(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	unwrapper := func(err error) []error {
+		if c, ok := err.(interface{ Causes() []error }); ok {
+			return c.Causes()
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name               string
+		givenOpts          []Option
+		givenErr           error
+		expectedStatusCode int
+	}{
+		{
+			name:               "without unwrapper",
+			givenErr:           multiCauseErr{causes: []error{errors.New("qux err"), errFoo}},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "with unwrapper",
+			givenOpts:          []Option{WithUnwrapper(unwrapper)},
+			givenErr:           multiCauseErr{causes: []error{errors.New("qux err"), errFoo}},
+			expectedStatusCode: http.StatusTeapot,
+		},
+		{
+			name:               "with unwrapper behind standard wrapping",
+			givenOpts:          []Option{WithUnwrapper(unwrapper)},
+			givenErr:           fmt.Errorf("wrapped: %w", multiCauseErr{causes: []error{errFoo}}),
+			expectedStatusCode: http.StatusTeapot,
+		},
+		{
+			name:      "with unwrapper and direct REST error cause",
+			givenOpts: []Option{WithUnwrapper(unwrapper)},
+			givenErr: multiCauseErr{causes: []error{RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			}}},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "with cyclic unwrapper",
+			givenOpts:          []Option{WithUnwrapper(func(err error) []error { return []error{err} })},
+			givenErr:           errors.New("qux err"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+		})
+	}
+}
+
+func TestHandleWithStatusCodeRewriter(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusBadGateway,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusNotFound,
+			Message:    errBar.Error(),
+		},
+	}
+
+	rewriter := func(statusCode int) int {
+		if statusCode >= 500 {
+			return http.StatusServiceUnavailable
+		}
+		return statusCode
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithStatusCodeRewriter(rewriter))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+	}{
+		{
+			name:               "mapped server error",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "mapped client error",
+			givenErr:           errBar,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("qux err"),
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "RESTErr sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusGatewayTimeout,
+				Message:    "timeout",
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+
+			var result RESTErr
+			require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+			assert.Equal(t, tc.expectedStatusCode, result.StatusCode)
+		})
+	}
+}
+
+func TestHandleWrappingDepth(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	wrappers := []struct {
+		name string
+		wrap func(err error, depth int) error
+	}{
+		{
+			name: "single unwrap",
+			wrap: func(err error, depth int) error {
+				return fmt.Errorf("layer%d: %w", depth, err)
+			},
+		},
+		{
+			name: "multi unwrap with join",
+			wrap: func(err error, depth int) error {
+				return errors.Join(fmt.Errorf("sibling%d", depth), err)
+			},
+		},
+		{
+			name: "multi unwrap with errorf",
+			wrap: func(err error, depth int) error {
+				return fmt.Errorf("layer%d: %w: %w", depth, errors.New("sibling"), err)
+			},
+		},
+		{
+			name: "alternating",
+			wrap: func(err error, depth int) error {
+				if depth%2 == 0 {
+					return fmt.Errorf("layer%d: %w", depth, err)
+				}
+				return errors.Join(err, fmt.Errorf("sibling%d", depth))
+			},
+		},
+	}
+
+	for _, wrapper := range wrappers {
+		for depth := 0; depth <= 5; depth++ {
+			t.Run(fmt.Sprintf("%s depth %d", wrapper.name, depth), func(t *testing.T) {
+				t.Parallel()
+
+				givenErr := errFoo
+				for i := 1; i <= depth; i++ {
+					givenErr = wrapper.wrap(givenErr, i)
+				}
+
+				writer := httptest.NewRecorder()
+
+				handler.Handle(context.TODO(), writer, givenErr)
+
+				assert.Equal(t, http.StatusTeapot, writer.Code)
+
+				var result RESTErr
+				require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+				assert.Equal(t, errorMap[errFoo], result)
+			})
+		}
+	}
+}
+
+func TestHandleInto(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			name:        "mapped error",
+			givenErr:    errFoo,
+			expectedErr: errorMap[errFoo],
+		},
+		{
+			name:        "unmapped error",
+			givenErr:    errors.New("qux err"),
+			expectedErr: internalErr,
+		},
+		{
+			name: "RESTErr sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+			expectedErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			var out RESTErr
+			handler.HandleInto(context.TODO(), writer, tc.givenErr, &out)
+
+			assert.Equal(t, tc.expectedErr.StatusCode, out.StatusCode)
+			assert.Equal(t, tc.expectedErr.Message, out.Message)
+			assert.Equal(t, writer.Code, out.StatusCode)
+		})
+	}
+}
+
+func TestHandleConcurrently(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+		errBar: {
+			StatusCode: http.StatusTooEarly,
+			Message:    errBar.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	inputs := []struct {
+		givenErr    error
+		expectedErr RESTErr
+	}{
+		{
+			givenErr:    errFoo,
+			expectedErr: errorMap[errFoo],
+		},
+		{
+			givenErr:    fmt.Errorf("wrapped: %w", errBar),
+			expectedErr: errorMap[errBar],
+		},
+		{
+			givenErr:    errors.New("qux err"),
+			expectedErr: internalErr,
+		},
+		{
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+			expectedErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+		},
+	}
+
+	const goroutines = 64
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				input := inputs[(i+j)%len(inputs)]
+
+				writer := httptest.NewRecorder()
+
+				handler.Handle(context.TODO(), writer, input.givenErr)
+
+				assert.Equal(t, input.expectedErr.StatusCode, writer.Code)
+
+				var result RESTErr
+				if assert.NoError(t, json.NewDecoder(writer.Body).Decode(&result)) {
+					assert.Equal(t, input.expectedErr, result)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The shared internal error JSON must not be altered by concurrent writes.
+	expected, err := json.Marshal(internalErr)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, handler.internalErrJSON)
+}
+
+func TestHandleErrors(t *testing.T) {
+	t.Parallel()
+
+	errEmail := errors.New("invalid email")
+	errName := errors.New("invalid name")
+
+	errorMap := map[error]RESTErr{
+		errEmail: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "email is invalid",
+			Details:    []Detail{{Field: "email", Message: "must contain @"}},
+		},
+		errName: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "name is required",
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.HandleErrors(context.TODO(), writer, http.StatusUnprocessableEntity,
+		fmt.Errorf("validate: %w", errEmail),
+		errName,
+		errors.New("qux err"),
+		RESTErr{StatusCode: http.StatusBadRequest, Message: "age must be positive"},
+	)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, writer.Code)
+
+	var result RESTErr
+	require.NoError(t, json.NewDecoder(writer.Body).Decode(&result))
+
+	expected := RESTErr{
+		StatusCode: http.StatusUnprocessableEntity,
+		Message:    http.StatusText(http.StatusUnprocessableEntity),
+		Details: []Detail{
+			{Field: "email", Message: "must contain @"},
+			{Message: "name is required"},
+			{Message: internalErr.Message},
+			{Message: "age must be positive"},
+		},
+	}
+
+	assert.Equal(t, expected, result)
+}
+
+func TestHandleErrorsWithNilErrors(t *testing.T) {
+	t.Parallel()
+
+	errName := errors.New("invalid name")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errName: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "name is required",
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("nil entries are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.HandleErrors(context.TODO(), writer, http.StatusUnprocessableEntity, nil, errName, nil)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, writer.Code)
+		assert.JSONEq(t, `{"status-code":422,"message":"Unprocessable Entity","details":[{"message":"name is required"}]}`, writer.Body.String())
+	})
+
+	t.Run("only nil entries", func(t *testing.T) {
+		t.Parallel()
+
+		writer := httptest.NewRecorder()
+		handler.HandleErrors(context.TODO(), writer, http.StatusUnprocessableEntity, nil, nil)
+
+		assert.Empty(t, writer.Body.String())
+		assert.Empty(t, writer.Header())
+	})
+}
+
+func TestHandleErrorsLogsOnce(t *testing.T) {
+	t.Parallel()
+
+	errEmail := errors.New("invalid email")
+
+	var logData strings.Builder
+
+	logWriter := mockLogWriter{
+		writeFunc: func(p []byte) (n int, err error) {
+			return logData.Write(p)
+		},
+	}
+
+	var handled []error
+
+	handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{
+		errEmail: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "email is invalid",
+		},
+	}, WithOnHandle(func(ctx context.Context, err error) {
+		handled = append(handled, err)
+	}))
+	require.NoError(t, err)
+
+	handler.HandleErrors(context.TODO(), httptest.NewRecorder(), http.StatusUnprocessableEntity, errEmail, errors.New("qux err"))
+
+	assert.Len(t, handled, 1)
+	assert.Equal(t, 1, strings.Count(logData.String(), "Handling"))
+	assert.Contains(t, logData.String(), `errors="[invalid email qux err]"`)
+}
+
+func TestHandleNDJSONItem(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusBadRequest,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+	writer.Header().Set("Content-Type", "application/x-ndjson")
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write([]byte(`{"index":0,"id":"a"}` + "\n"))
+	require.NoError(t, err)
+
+	handler.HandleNDJSONItem(context.TODO(), writer, 1, fmt.Errorf("line 1: %w", errFoo))
+	handler.HandleNDJSONItem(context.TODO(), writer, 2, errors.New("qux err"))
+	handler.HandleNDJSONItem(context.TODO(), writer, 3, nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Equal(t, "application/x-ndjson", writer.Header().Get("Content-Type"))
+	assert.True(t, writer.Flushed)
+
+	expected := `{"index":0,"id":"a"}` + "\n" +
+		`{"index":1,"error":{"status-code":400,"message":"foo err"}}` + "\n" +
+		`{"index":2,"error":{"status-code":500,"message":"something went wrong"}}` + "\n"
+
+	assert.Equal(t, expected, writer.Body.String())
+}
+
+func TestHandleNDJSONItemWithMaxMessageLengthAndResponseValidator(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+	errBar := errors.New("bar err")
+
+	validator := func(body []byte) error {
+		if strings.Contains(string(body), "forbidden") {
+			return errors.New("forbidden word")
+		}
+		return nil
+	}
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusBadRequest, Message: "query failed: SELECT * FROM users"},
+		errBar: {StatusCode: http.StatusBadRequest, Message: "forbidden"},
+	}, WithMaxMessageLength(12), WithResponseValidator(validator))
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.HandleNDJSONItem(context.TODO(), writer, 0, errFoo)
+	handler.HandleNDJSONItem(context.TODO(), writer, 1, errBar)
+
+	expected := `{"index":0,"error":{"status-code":400,"message":"query faile…"}}` + "\n" +
+		`{"index":1,"error":{"status-code":500,"message":"something went wrong"}}` + "\n"
+
+	assert.Equal(t, expected, writer.Body.String())
+}
+
+func TestHandleRequest(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2024, time.May, 10, 12, 30, 0, 0, time.UTC)
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode:   http.StatusNotFound,
+			Message:      errFoo.Error(),
+			LastModified: lastModified,
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenMethod        string
+		givenIMS           string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       bool
+	}{
+		{
+			name:               "no conditional header",
+			givenMethod:        http.MethodGet,
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       true,
+		},
+		{
+			name:               "not modified since",
+			givenMethod:        http.MethodGet,
+			givenIMS:           lastModified.Format(http.TimeFormat),
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:               "modified since",
+			givenMethod:        http.MethodGet,
+			givenIMS:           lastModified.Add(-time.Hour).Format(http.TimeFormat),
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       true,
+		},
+		{
+			name:               "invalid conditional header",
+			givenMethod:        http.MethodGet,
+			givenIMS:           "yesterday",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       true,
+		},
+		{
+			name:               "non GET request",
+			givenMethod:        http.MethodPost,
+			givenIMS:           lastModified.Format(http.TimeFormat),
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       true,
+		},
+		{
+			name:               "unmapped error",
+			givenMethod:        http.MethodGet,
+			givenIMS:           lastModified.Format(http.TimeFormat),
+			givenErr:           errors.New("qux error"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.givenMethod, "/foo", nil)
+			if tc.givenIMS != "" {
+				req.Header.Set("If-Modified-Since", tc.givenIMS)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, tc.expectedBody, writer.Body.Len() > 0)
+
+			if tc.givenErr == errFoo {
+				assert.Equal(t, lastModified.Format(http.TimeFormat), writer.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
+
+func TestHandleRequestHead(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       RESTErr
+	}{
+		{
+			name:               "mapped error",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       errorMap[errFoo],
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("qux err"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       internalErr,
+		},
+		{
+			name: "RESTErr sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "conflict",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectedBody, err := json.Marshal(tc.expectedBody)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, httptest.NewRequest(http.MethodHead, "/foo", nil), tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, "application/json", writer.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(len(expectedBody)), writer.Header().Get("Content-Length"))
+			assert.Empty(t, writer.Body.Bytes())
+		})
+	}
+}
+
+func TestHandleTrailer(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusConflict,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		givenErr      error
+		expectedValue string
+	}{
+		{
+			name:          "mapped error",
+			givenErr:      errFoo,
+			expectedValue: "409 foo err",
+		},
+		{
+			name:          "unmapped error",
+			givenErr:      errors.New("qux err"),
+			expectedValue: "500 something went wrong",
+		},
+		{
+			name: "RESTErr sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusGone,
+				Message:    "gone",
+			},
+			expectedValue: "410 gone",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			writer.Header().Set("Trailer", "X-Stream-Error")
+
+			_, err := writer.Write([]byte("partial stream"))
+			require.NoError(t, err)
+
+			handler.HandleTrailer(context.TODO(), writer, tc.givenErr, "X-Stream-Error")
+
+			assert.Equal(t, http.StatusOK, writer.Result().StatusCode)
+			assert.Equal(t, tc.expectedValue, writer.Result().Trailer.Get("X-Stream-Error"))
+		})
+	}
+}
+
+// bodyStatusCodes are the status codes of responses that may have a body.
+// Informational responses, 204 No Content and 304 Not Modified never have one.
+var bodyStatusCodes = func() []int {
+	var codes []int
+	for code := 200; code < 600; code++ {
+		if http.StatusText(code) != "" && code != http.StatusNoContent && code != http.StatusNotModified {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}()
+
+// TestStatusMatchesBody checks that the status code written with WriteHeader always equals
+// the status code in the body, for random mappings, errors and options.
+func TestHandleTrailerWithMaxMessageLength(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithMaxMessageLength(12))
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+	writer.Header().Set("Trailer", "X-Stream-Error")
+
+	handler.HandleTrailer(context.TODO(), writer, RESTErr{StatusCode: http.StatusConflict, Message: "créneau déjà réservé"}, "X-Stream-Error")
+
+	assert.Equal(t, "409 créneau déj…", writer.Header().Get("X-Stream-Error"))
+}
+
+func TestStatusMatchesBody(t *testing.T) {
+	t.Parallel()
+
+	const iterations = 500
+
+	for i := 0; i < iterations; i++ {
+		seed := uint64(i)
+		rnd := rand.New(rand.NewPCG(seed, seed))
+
+		randomStatus := func() int {
+			return bodyStatusCodes[rnd.IntN(len(bodyStatusCodes))]
+		}
+
+		keys := make([]error, 1+rnd.IntN(5))
+		errorMap := make(map[error]RESTErr, len(keys))
+		for j := range keys {
+			keys[j] = fmt.Errorf("err %d", j)
+			errorMap[keys[j]] = RESTErr{StatusCode: randomStatus(), Message: keys[j].Error()}
+		}
+
+		var (
+			opts         []Option
+			statusHeader string
+			problem      = rnd.IntN(2) == 0
+		)
+
+		if problem {
+			opts = append(opts, WithProblemDetails())
+		}
+
+		if rnd.IntN(2) == 0 {
+			rewrites := map[int]int{randomStatus(): randomStatus(), http.StatusInternalServerError: randomStatus()}
+			opts = append(opts, WithStatusCodeRewriter(func(statusCode int) int {
+				if rewritten, ok := rewrites[statusCode]; ok {
+					return rewritten
+				}
+				return statusCode
+			}))
+		}
+
+		if rnd.IntN(2) == 0 {
+			opts = append(opts, WithFallbackRESTErr(RESTErr{StatusCode: randomStatus(), Message: "fallback"}))
+		}
+
+		if rnd.IntN(2) == 0 {
+			opts = append(opts, WithStatusFromHeader("X-Upstream-Status"))
+			statusHeader = strconv.Itoa(randomStatus())
+		}
+
+		if rnd.IntN(2) == 0 {
+			opts = append(opts, WithDefaultSeverity())
+		}
+
+		handler, err := NewHandler(logger, errorMap, opts...)
+		require.NoError(t, err, "seed %d", seed)
+
+		key := keys[rnd.IntN(len(keys))]
+		givenErrs := []error{
+			key,
+			fmt.Errorf("wrapped: %w", key),
+			RESTErr{StatusCode: randomStatus(), Message: "direct"},
+			WithStatus(errors.New("inline"), randomStatus()),
+			statusCoderErr{statusCode: randomStatus()},
+			errors.New("unmapped"),
+		}
+
+		for _, givenErr := range givenErrs {
+			writer := httptest.NewRecorder()
+
+			if statusHeader != "" {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Upstream-Status", statusHeader)
+				handler.HandleRequest(writer, req, givenErr)
+			} else {
+				handler.Handle(context.TODO(), writer, givenErr)
+			}
+
+			var body struct {
+				StatusCode int `json:"status-code"`
+				Status     int `json:"status"`
+			}
+			require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &body), "seed %d, error %v", seed, givenErr)
+
+			bodyStatus := body.StatusCode
+			if problem {
+				bodyStatus = body.Status
+			}
+			require.Equal(t, writer.Code, bodyStatus, "seed %d, error %v", seed, givenErr)
+		}
+	}
+}
+
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *discardWriter) WriteHeader(statusCode int) {}
+
+func (d *discardWriter) Header() http.Header {
+	return d.header
+}
+
+// hotPath is a path of Handle guarded by BenchmarkHandle and the allocation budget.
+type hotPath struct {
+	name     string
+	givenErr error
+}
+
+// hotPaths returns the handler and the paths of Handle measured by BenchmarkHandle and TestAllocBudget.
+// Their names are the keys of the allocation budget and of the benchmark baseline, so they must not change.
+func hotPaths(tb testing.TB) (*Handler, []hotPath) {
+	tb.Helper()
+
+	errFoo := errors.New("foo err")
+
+	errorMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTeapot,
+			Message:    errFoo.Error(),
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap)
+	require.NoError(tb, err)
+
+	return handler, []hotPath{
+		{
+			name: "nil error",
+		},
+		{
+			name:     "mapped error",
+			givenErr: errFoo,
+		},
+		{
+			name:     "wrapped mapped error",
+			givenErr: fmt.Errorf("wrapped: %w", errFoo),
+		},
+		{
+			name:     "unmapped error",
+			givenErr: errors.New("qux err"),
+		},
+		{
+			name:     "RESTErr sent directly to handler",
+			givenErr: RESTErr{StatusCode: http.StatusConflict, Message: "conflict"},
+		},
+		{
+			name: "RESTErr with details sent directly to handler",
+			givenErr: RESTErr{
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "invalid form",
+				Details: []Detail{
+					{Field: "email", Message: "must be a valid email address"},
+					{Field: "name", Message: "must not be empty"},
+				},
+			},
+		},
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	handler, paths := hotPaths(b)
+
+	for _, bm := range paths {
+		b.Run(bm.name, func(b *testing.B) {
+			w := discardWriter{header: http.Header{"Content-Type": []string{"application/json"}}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				handler.Handle(context.TODO(), &w, bm.givenErr)
+			}
+		})
+	}
+}
+
+func TestWriteEncodedRESTErr(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	givenErrs := []RESTErr{
+		{StatusCode: http.StatusConflict, Message: "a longer conflict message"},
+		{StatusCode: http.StatusGone, Message: "gone"},
+	}
+
+	// Buffers are reused, so that every response must only hold its own error.
+	for i := 0; i < 10; i++ {
+		for _, givenErr := range givenErrs {
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, givenErr)
+
+			expected, err := json.Marshal(givenErr)
+			require.NoError(t, err)
+
+			assert.Equal(t, string(expected), writer.Body.String())
+		}
+	}
+}
+
+func TestWriteInternalErr(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(slog.Default(), map[error]RESTErr{})
+	require.NoError(t, err)
+
+	payload := httptest.NewRecorder()
+
+	handler.writeInternalErr(context.TODO(), payload)
+
+	assert.Equal(t, http.StatusInternalServerError, payload.Result().StatusCode)
+
+	var result RESTErr
+	require.NoError(t, json.NewDecoder(payload.Result().Body).Decode(&result))
+
+	assert.Equal(t, internalErr, result)
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	errWithJSON := RESTErr{
+		StatusCode: 123,
+		Message:    "foo-message",
+	}
+
+	b, err := json.Marshal(errWithJSON)
+	require.NoError(t, err)
+
+	errWithJSON.json = b
+
+	errWithoutJSON := RESTErr{
+		StatusCode: 456,
+		Message:    "bar-message",
+	}
+
+	bb, err := json.Marshal(errWithoutJSON)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenErr     RESTErr
+		expectedJSON []byte
+	}{
+		{
+			name:         "error contain pre-compiled JSON",
+			givenErr:     errWithJSON,
+			expectedJSON: b,
+		},
+		{
+			name:         "error does not contain pre-compiled JSON",
+			givenErr:     errWithoutJSON,
+			expectedJSON: bb,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				writeHeaderCalled bool
+				writeCalled       bool
+				header            = http.Header{}
+			)
+
+			w := mockLogWriter{
+				headerFunc: func() http.Header {
+					return header
+				},
 				writeHeaderFunc: func(statusCode int) {
 					writeHeaderCalled = true
 					assert.Equal(t, tc.givenErr.StatusCode, statusCode)
@@ -279,6 +4735,7 @@ func TestWrite(t *testing.T) {
 
 			require.True(t, writeHeaderCalled)
 			require.True(t, writeCalled)
+			assert.Equal(t, "application/json", header.Get("Content-Type"))
 		})
 	}
 }
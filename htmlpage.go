@@ -0,0 +1,68 @@
+package resterr
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+)
+
+// WithDefaultHTMLErrorPage is an option to let HandleRequest serve page, an HTML document,
+// instead of REST errors with statusCode to clients preferring HTML over JSON, such as browsers
+// hitting API endpoints by accident. API clients still get the REST error. A zero statusCode
+// sets the page of every server error (5xx) without a page of its own, and an empty page uses
+// a built-in minimal page showing the status. Only server errors get HTML pages.
+func WithDefaultHTMLErrorPage(statusCode int, page string) Option {
+	return func(h *Handler) {
+		if h.htmlPages == nil {
+			h.htmlPages = make(map[int]string)
+		}
+		h.htmlPages[statusCode] = page
+	}
+}
+
+// htmlPage returns the HTML page to serve for e to r, if r prefers HTML and e calls for one.
+func (h *Handler) htmlPage(r *http.Request, e RESTErr) (string, bool) {
+	if len(h.htmlPages) == 0 || e.StatusCode < http.StatusInternalServerError {
+		return "", false
+	}
+
+	page, ok := h.htmlPages[e.StatusCode]
+	if !ok {
+		if page, ok = h.htmlPages[0]; !ok {
+			return "", false
+		}
+	}
+
+	if !prefersHTML(r.Header.Values("Accept")) {
+		return "", false
+	}
+
+	if page == "" {
+		page = defaultHTMLPage(e.StatusCode)
+	}
+	return page, true
+}
+
+// defaultHTMLPage returns the built-in HTML page for statusCode.
+func defaultHTMLPage(statusCode int) string {
+	title := html.EscapeString(fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)))
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>%s</title></head>
+<body><h1>%s</h1><p>Something went wrong on our side. Please try again later.</p></body>
+</html>
+`, title, title)
+}
+
+// writeHTML writes page as the response for e, with the headers of e.
+func (h *Handler) writeHTML(ctx context.Context, w Writer, e RESTErr, page string) {
+	h.delay(ctx, e)
+
+	h.writeHeader(ctx, w, e.StatusCode, "text/html; charset=utf-8", e.Headers)
+	if _, err := w.Write([]byte(page)); err != nil {
+		h.logger.ErrorContext(ctx, "Failed to write HTML error page.", slog.String("source-error", e.Error()), slog.String(h.errKey, err.Error()))
+	}
+	h.flush(w)
+}
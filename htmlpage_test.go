@@ -0,0 +1,119 @@
+package resterr
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRequestWithDefaultHTMLErrorPage(t *testing.T) {
+	t.Parallel()
+
+	errUnavailable := errors.New("unavailable err")
+	errNotFound := errors.New("not found err")
+
+	errMap := map[error]RESTErr{
+		errUnavailable: {
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "service unavailable",
+		},
+		errNotFound: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}
+
+	const maintenancePage = "<html><body>Down for maintenance</body></html>"
+
+	testCases := []struct {
+		name                string
+		givenOpts           []Option
+		givenAccept         string
+		givenErr            error
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "browser with a page for the status",
+			givenOpts:           []Option{WithDefaultHTMLErrorPage(http.StatusServiceUnavailable, maintenancePage)},
+			givenAccept:         "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			givenErr:            errUnavailable,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        maintenancePage,
+		},
+		{
+			name:                "browser with the built-in page",
+			givenOpts:           []Option{WithDefaultHTMLErrorPage(0, "")},
+			givenAccept:         "text/html",
+			givenErr:            errors.New("foo err"),
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        defaultHTMLPage(http.StatusInternalServerError),
+		},
+		{
+			name:                "browser without a page for the status",
+			givenOpts:           []Option{WithDefaultHTMLErrorPage(http.StatusServiceUnavailable, maintenancePage)},
+			givenAccept:         "text/html",
+			givenErr:            errors.New("foo err"),
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":500,"message":"something went wrong"}`,
+		},
+		{
+			name:                "browser with a client error",
+			givenOpts:           []Option{WithDefaultHTMLErrorPage(0, "")},
+			givenAccept:         "text/html",
+			givenErr:            errNotFound,
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"not found"}`,
+		},
+		{
+			name:                "API client",
+			givenOpts:           []Option{WithDefaultHTMLErrorPage(0, "")},
+			givenAccept:         "application/json",
+			givenErr:            errUnavailable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":503,"message":"service unavailable"}`,
+		},
+		{
+			name:                "without pages",
+			givenAccept:         "text/html",
+			givenErr:            errUnavailable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":503,"message":"service unavailable"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, errMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tc.givenAccept)
+
+			writer := httptest.NewRecorder()
+			handler.HandleRequest(writer, req, tc.givenErr)
+
+			assert.Equal(t, tc.expectedContentType, writer.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+
+			if len(tc.givenOpts) > 0 {
+				assert.Equal(t, "Accept", writer.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestDefaultHTMLPage(t *testing.T) {
+	t.Parallel()
+
+	page := defaultHTMLPage(http.StatusBadGateway)
+
+	assert.Contains(t, page, "<title>502 Bad Gateway</title>")
+	assert.Contains(t, page, "<h1>502 Bad Gateway</h1>")
+}
@@ -0,0 +1,23 @@
+package resterr
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+)
+
+// idEncoding encodes IDs with the lowercase base32 alphabet, without padding,
+// so that they are safe in headers, URLs and log lines.
+var idEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// DefaultIDGenerator returns a function generating random IDs, such as "mfrggzdfmztwq2lk",
+// for WithErrorID and WithRequestIDGenerator, which use it when given a nil function.
+// The IDs encode 80 random bits, which is enough to correlate responses with logs
+// without collisions. The function is safe for concurrent use.
+func DefaultIDGenerator() func() string {
+	return func() string {
+		var b [10]byte
+		// crypto/rand.Read never fails on supported platforms.
+		_, _ = rand.Read(b[:])
+		return idEncoding.EncodeToString(b[:])
+	}
+}
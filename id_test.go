@@ -0,0 +1,89 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("format", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Regexp(t, regexp.MustCompile(`^[a-z2-7]{16}$`), DefaultIDGenerator()())
+	})
+
+	t.Run("concurrent IDs are unique", func(t *testing.T) {
+		t.Parallel()
+
+		generate := DefaultIDGenerator()
+
+		var (
+			mu  sync.Mutex
+			wg  sync.WaitGroup
+			ids = make(map[string]struct{})
+		)
+
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for range 1000 {
+					id := generate()
+
+					mu.Lock()
+					ids[id] = struct{}{}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, ids, 8000)
+	})
+
+	t.Run("used by nil error ID functions", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{}, WithErrorID(nil))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errors.New("foo err"))
+
+		assert.Regexp(t, regexp.MustCompile(`"error-id":"[a-z2-7]{16}"`), writer.Body.String())
+	})
+
+	t.Run("used by nil request ID generators", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, map[error]RESTErr{}, WithEchoRequestID("X-Request-ID"), WithRequestIDGenerator(nil))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.HandleRequest(writer, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("foo err"))
+
+		assert.Regexp(t, regexp.MustCompile(`^[a-z2-7]{16}$`), writer.Header().Get("X-Request-ID"))
+	})
+}
+
+func BenchmarkDefaultIDGenerator(b *testing.B) {
+	generate := DefaultIDGenerator()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = generate()
+		}
+	})
+}
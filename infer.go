@@ -0,0 +1,106 @@
+package resterr
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// inferredStatuses maps conventional error name suffixes to the status codes they result in.
+// Suffixes are normalized: lowercase letters only.
+var inferredStatuses = []struct {
+	suffix     string
+	statusCode int
+}{
+	{suffix: "notfound", statusCode: http.StatusNotFound},
+	{suffix: "conflict", statusCode: http.StatusConflict},
+	{suffix: "unauthorized", statusCode: http.StatusUnauthorized},
+	{suffix: "forbidden", statusCode: http.StatusForbidden},
+	{suffix: "invalid", statusCode: http.StatusBadRequest},
+	{suffix: "badrequest", statusCode: http.StatusBadRequest},
+}
+
+// InferStatus infers the status code of an error from its name, recognizing common suffixes:
+// NotFound (404), Conflict (409), Unauthorized (401), Forbidden (403), and Invalid or BadRequest (400).
+// Since the names of sentinel variables are not available at runtime, the message of the error
+// is used, so that errors.New("user not found") results in 404. Errors of other types than
+// the ones created by errors.New and fmt.Errorf are also recognized by the name of their type,
+// such as NotFoundError. The returned bool reports whether the status code could be inferred.
+func InferStatus(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	names := []string{err.Error()}
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if pkg := t.PkgPath(); pkg != "errors" && pkg != "fmt" && t.Name() != "" {
+		names = append(names, t.Name())
+	}
+
+	for _, name := range names {
+		name = normalizeName(name)
+		for _, s := range inferredStatuses {
+			if strings.HasSuffix(name, s.suffix) {
+				return s.statusCode, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// normalizeName lowercases name and strips anything but letters,
+// including a trailing "error" or "err" as in type names like NotFoundError.
+func normalizeName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	normalized := sb.String()
+	for _, suffix := range []string{"error", "err"} {
+		if trimmed, ok := strings.CutSuffix(normalized, suffix); ok {
+			return trimmed
+		}
+	}
+	return normalized
+}
+
+// AutoRegister is an option to map conventionally named errors to REST errors without
+// declaring each mapping. The status code of every error is inferred by InferStatus
+// and the message is the message of the error. Mappings in the error map always take
+// precedence, and NewHandler fails for errors whose status code cannot be inferred
+// and that are not mapped otherwise.
+func AutoRegister(errs ...error) Option {
+	return func(h *Handler) {
+		h.autoErrors = append(h.autoErrors, errs...)
+	}
+}
+
+// autoRegistered returns the REST errors of the errors registered with AutoRegister
+// that are not in errMap.
+func autoRegistered(errMap map[error]RESTErr, errs []error) (map[error]RESTErr, error) {
+	registered := make(map[error]RESTErr, len(errs))
+	for _, err := range errs {
+		if _, ok := errMap[err]; ok {
+			continue
+		}
+
+		statusCode, ok := InferStatus(err)
+		if !ok {
+			return nil, fmt.Errorf("could not infer status code of error '%v'", err)
+		}
+
+		registered[err] = RESTErr{
+			StatusCode: statusCode,
+			Message:    err.Error(),
+		}
+	}
+	return registered, nil
+}
@@ -0,0 +1,158 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type NotFoundError struct{}
+
+func (NotFoundError) Error() string { return "no such thing" }
+
+func TestInferStatus(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedOK         bool
+	}{
+		{
+			name:               "not found",
+			givenErr:           errors.New("user not found"),
+			expectedStatusCode: http.StatusNotFound,
+			expectedOK:         true,
+		},
+		{
+			name:               "conflict",
+			givenErr:           errors.New("email conflict"),
+			expectedStatusCode: http.StatusConflict,
+			expectedOK:         true,
+		},
+		{
+			name:               "unauthorized",
+			givenErr:           errors.New("Unauthorized"),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedOK:         true,
+		},
+		{
+			name:               "forbidden",
+			givenErr:           errors.New("access forbidden"),
+			expectedStatusCode: http.StatusForbidden,
+			expectedOK:         true,
+		},
+		{
+			name:               "invalid",
+			givenErr:           errors.New("email invalid"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOK:         true,
+		},
+		{
+			name:               "bad request",
+			givenErr:           errors.New("bad_request"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOK:         true,
+		},
+		{
+			name:               "error suffix",
+			givenErr:           errors.New("not found error"),
+			expectedStatusCode: http.StatusNotFound,
+			expectedOK:         true,
+		},
+		{
+			name:               "wrapped",
+			givenErr:           fmt.Errorf("lookup: %w", errors.New("not found")),
+			expectedStatusCode: http.StatusNotFound,
+			expectedOK:         true,
+		},
+		{
+			name:               "type name",
+			givenErr:           NotFoundError{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedOK:         true,
+		},
+		{
+			name:     "unknown",
+			givenErr: errors.New("database is down"),
+		},
+		{
+			name: "nil",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statusCode, ok := InferStatus(tc.givenErr)
+
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedStatusCode, statusCode)
+		})
+	}
+}
+
+func TestAutoRegister(t *testing.T) {
+	t.Parallel()
+
+	errUserNotFound := errors.New("user not found")
+	errEmailConflict := errors.New("email conflict")
+	errDown := errors.New("database is down")
+
+	t.Run("infers mappings", func(t *testing.T) {
+		t.Parallel()
+
+		handler, err := NewHandler(logger, nil, AutoRegister(errUserNotFound, errEmailConflict))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errUserNotFound)
+
+		assert.Equal(t, http.StatusNotFound, writer.Code)
+		assert.JSONEq(t, `{"status-code":404,"message":"user not found"}`, writer.Body.String())
+
+		writer = httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errEmailConflict)
+
+		assert.Equal(t, http.StatusConflict, writer.Code)
+	})
+
+	t.Run("explicit mappings win", func(t *testing.T) {
+		t.Parallel()
+
+		errorMap := map[error]RESTErr{
+			errUserNotFound: {
+				StatusCode: http.StatusGone,
+				Message:    "user deleted",
+			},
+			errDown: {
+				StatusCode: http.StatusServiceUnavailable,
+				Message:    "try again later",
+			},
+		}
+
+		handler, err := NewHandler(logger, errorMap, AutoRegister(errUserNotFound, errDown))
+		require.NoError(t, err)
+
+		writer := httptest.NewRecorder()
+		handler.Handle(context.TODO(), writer, errUserNotFound)
+
+		assert.Equal(t, http.StatusGone, writer.Code)
+		assert.JSONEq(t, `{"status-code":410,"message":"user deleted"}`, writer.Body.String())
+	})
+
+	t.Run("fails for unknown names", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewHandler(logger, nil, AutoRegister(errDown))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,110 @@
+package resterr
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var notFoundErr = RESTErr{
+	StatusCode: http.StatusNotFound,
+	Message:    "resource not found",
+}
+
+// NotFoundHandlerWithFallback returns an HTTP handler for routes that do not exist, meant to be
+// given to routers as their 404 handler. Requests are content negotiated with the Accept header:
+// clients preferring HTML over JSON, such as browsers, are served by fallback, for instance
+// with a static 404 page, while API clients receive a 404 Not Found REST error.
+// A nil fallback results in REST errors for every request. Otherwise, responses vary on Accept,
+// so that shared caches do not serve the HTML page to API clients.
+func (h *Handler) NotFoundHandlerWithFallback(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fallback != nil {
+			varyAccept(w.Header())
+		}
+
+		if fallback != nil && prefersHTML(r.Header.Values("Accept")) {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		h.HandleRequest(w, r, notFoundErr)
+	})
+}
+
+// varyAccept adds Accept to the Vary header of responses negotiated with the Accept header,
+// unless it is already there.
+func varyAccept(header http.Header) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "Accept") {
+				return
+			}
+		}
+	}
+	header.Add("Vary", "Accept")
+}
+
+// prefersHTML reports whether the media ranges of Accept header values give HTML
+// a higher quality than JSON. Missing headers accept anything, which favors JSON.
+func prefersHTML(accept []string) bool {
+	var htmlQ, jsonQ float64
+	var htmlSpecificity, jsonSpecificity int
+
+	for _, v := range accept {
+		for _, mediaRange := range strings.Split(v, ",") {
+			mediaType, q, ok := parseMediaRange(mediaRange)
+			if !ok {
+				continue
+			}
+
+			if s := specificity(mediaType, "text", "html"); s > htmlSpecificity {
+				htmlQ, htmlSpecificity = q, s
+			}
+
+			if s := specificity(mediaType, "application", "json"); s > jsonSpecificity {
+				jsonQ, jsonSpecificity = q, s
+			}
+		}
+	}
+	return htmlQ > jsonQ
+}
+
+// parseMediaRange returns the media type and quality of a media range of an Accept header.
+func parseMediaRange(mediaRange string) (string, float64, bool) {
+	mediaType, params, _ := strings.Cut(mediaRange, ";")
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return "", 0, false
+	}
+
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(key) != "q" {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", 0, false
+		}
+		q = parsed
+	}
+	return mediaType, q, true
+}
+
+// specificity returns how precisely mediaType matches typ/subtype: 3 for an exact match,
+// 2 for typ/*, 1 for */* and 0 when it does not match.
+func specificity(mediaType, typ, subtype string) int {
+	switch mediaType {
+	case typ + "/" + subtype:
+		return 3
+	case typ + "/*":
+		return 2
+	case "*/*":
+		return 1
+	default:
+		return 0
+	}
+}
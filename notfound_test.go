@@ -0,0 +1,118 @@
+package resterr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotFoundHandlerWithFallback(t *testing.T) {
+	t.Parallel()
+
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "<h1>Not Found</h1>")
+	})
+
+	testCases := []struct {
+		name                string
+		givenFallback       http.Handler
+		givenAccept         []string
+		expectedContentType string
+		expectedBody        string
+		expectedVary        []string
+	}{
+		{
+			name:                "no accept header",
+			givenFallback:       fallback,
+			expectedVary:        []string{"Accept"},
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"resource not found"}`,
+		},
+		{
+			name:                "api client",
+			givenFallback:       fallback,
+			expectedVary:        []string{"Accept"},
+			givenAccept:         []string{"application/json"},
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"resource not found"}`,
+		},
+		{
+			name:                "browser",
+			givenFallback:       fallback,
+			expectedVary:        []string{"Accept"},
+			givenAccept:         []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			expectedContentType: "text/html",
+			expectedBody:        "<h1>Not Found</h1>",
+		},
+		{
+			name:                "json preferred over html",
+			givenFallback:       fallback,
+			expectedVary:        []string{"Accept"},
+			givenAccept:         []string{"text/html;q=0.5", "application/json"},
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"resource not found"}`,
+		},
+		{
+			name:                "html type range",
+			givenFallback:       fallback,
+			expectedVary:        []string{"Accept"},
+			givenAccept:         []string{"text/*, application/json;q=0.1"},
+			expectedContentType: "text/html",
+			expectedBody:        "<h1>Not Found</h1>",
+		},
+		{
+			name:                "nil fallback",
+			givenAccept:         []string{"text/html"},
+			expectedContentType: "application/json",
+			expectedBody:        `{"status-code":404,"message":"resource not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, nil)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			for _, v := range tc.givenAccept {
+				req.Header.Add("Accept", v)
+			}
+
+			writer := httptest.NewRecorder()
+			handler.NotFoundHandlerWithFallback(tc.givenFallback).ServeHTTP(writer, req)
+
+			assert.Equal(t, http.StatusNotFound, writer.Code)
+			assert.Equal(t, tc.expectedContentType, writer.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedVary, writer.Header().Values("Vary"))
+
+			if tc.expectedContentType == "application/json" {
+				assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+			} else {
+				assert.Equal(t, tc.expectedBody, writer.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotFoundHandlerWithFallbackAndHTMLErrorPages(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, nil, WithDefaultHTMLErrorPage(http.StatusNotFound, "<h1>Not Found</h1>"))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+
+	writer := httptest.NewRecorder()
+	handler.NotFoundHandlerWithFallback(http.NotFoundHandler()).ServeHTTP(writer, req)
+
+	assert.Equal(t, []string{"Accept"}, writer.Header().Values("Vary"))
+}
@@ -0,0 +1,36 @@
+package resterr
+
+// Preset is a reusable set of options, to keep error handling consistent across services.
+// Since it is a slice of options, it can be passed to NewHandler as is:
+//
+//	resterr.NewHandler(logger, errMap, resterr.PublicAPIPreset()...)
+type Preset []Option
+
+// PublicAPIPreset returns options suited to public APIs: error responses are never cached
+// by intermediaries and malformed request bodies result in 400 Bad Request.
+func PublicAPIPreset() Preset {
+	return Preset{
+		WithNoStore(),
+		WithStandardErrors(),
+	}
+}
+
+// ExtendedStatusesPreset returns options mapping the errors of HTTP statuses that are rarely
+// handled but defined by the specification, with WithExtendedStatuses, so that a gateway
+// detecting such a condition returns a clean response rather than an internal server error.
+func ExtendedStatusesPreset() Preset {
+	return Preset{
+		WithExtendedStatuses(),
+	}
+}
+
+// ProblemDetailsPreset returns options suited to APIs standardized on RFC 9457: errors are
+// written as problem details documents, are never cached by intermediaries, and server errors
+// carry an error ID generated by DefaultIDGenerator for support teams to trace them.
+func ProblemDetailsPreset() Preset {
+	return Preset{
+		WithProblemDetails(),
+		WithNoStore(),
+		WithErrorID(nil),
+	}
+}
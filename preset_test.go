@@ -0,0 +1,105 @@
+package resterr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicAPIPreset(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, PublicAPIPreset()...)
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, fmt.Errorf("could not decode body: %w", io.ErrUnexpectedEOF))
+
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+	assert.Equal(t, "no-store", writer.Header().Get("Cache-Control"))
+}
+
+func TestExtendedStatusesPreset(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		ErrRateLimited: {
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "slow down",
+		},
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}, ExtendedStatusesPreset()...)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenErr     error
+		expectedCode int
+	}{
+		{
+			name:         "header too large",
+			givenErr:     fmt.Errorf("could not proxy request: %w", ErrHeaderTooLarge),
+			expectedCode: http.StatusRequestHeaderFieldsTooLarge,
+		},
+		{
+			name:         "legal block",
+			givenErr:     ErrLegalBlock,
+			expectedCode: http.StatusUnavailableForLegalReasons,
+		},
+		{
+			name:         "overridden by the error map",
+			givenErr:     ErrRateLimited,
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "error map",
+			givenErr:     errFoo,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedCode, writer.Code)
+		})
+	}
+}
+
+func TestProblemDetailsPreset(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, ProblemDetailsPreset()...)
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, errors.New("foo err"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &body))
+
+	assert.Equal(t, http.StatusInternalServerError, writer.Code)
+	assert.Equal(t, "application/problem+json", writer.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", writer.Header().Get("Cache-Control"))
+	assert.Equal(t, "about:blank", body["type"])
+	assert.NotEmpty(t, body["error-id"])
+}
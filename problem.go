@@ -0,0 +1,212 @@
+package resterr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// problemContentType is the media type of problem details documents.
+const problemContentType = "application/problem+json"
+
+// problemDocument is a problem details document as defined by RFC 9457.
+// Members other than the ones of the RFC are extension members.
+type problemDocument struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title,omitempty"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Code     int      `json:"code,omitempty"`
+	ErrorID  string   `json:"error-id,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Details  []Detail `json:"details,omitempty"`
+
+	DetailsTruncated bool `json:"details-truncated,omitempty"`
+}
+
+// problemMembers are the members of problem details documents written by the handler,
+// which other members must not collide with.
+var problemMembers = []string{
+	"type", "title", "status", "detail", "instance",
+	"code", "error-id", "severity", "details", "details-truncated",
+}
+
+// defaultProblemParamsKey is the member holding the details of REST errors in problem details documents.
+const defaultProblemParamsKey = "details"
+
+// WithProblemDetails is an option to write REST errors as problem details documents conforming
+// to RFC 9457, with the application/problem+json content type. The message is the detail member
+// and the status member always matches the status code of the response. The type member defaults
+// to "about:blank" and the title member to the standard status text. The code, error ID, severity
+// and details of REST errors are extension members, and so are the members of their Extension.
+// HandleRequest resolves relative type and instance URI references against the request URL.
+// It replaces the format of WithMinimalFormat when given after it, and the other way around.
+func WithProblemDetails() Option {
+	return func(h *Handler) {
+		h.problemDetails = true
+		h.marshalFn = nil
+	}
+}
+
+// WithProblemDetailsParamsKey is an option to set the member of problem details documents holding
+// the details of REST errors, which is "details" by default, such as "invalid-params" for gateways
+// following the IETF draft on validation problems. A key colliding with another member of the
+// documents, such as "status", makes NewHandler fail.
+func WithProblemDetailsParamsKey(key string) Option {
+	return func(h *Handler) {
+		h.problemParamsKey = key
+	}
+}
+
+func (h *Handler) marshalProblem(e RESTErr) ([]byte, error) {
+	doc := problemDocument{
+		Type:     e.Type,
+		Title:    e.Title,
+		Status:   e.StatusCode,
+		Detail:   e.Message,
+		Instance: e.Instance,
+		Code:     e.Code,
+		ErrorID:  e.ErrorID,
+		Severity: e.Severity,
+		Details:  e.Details,
+
+		DetailsTruncated: e.detailsTruncated,
+	}
+
+	if doc.Type == "" {
+		doc.Type = "about:blank"
+	}
+
+	if doc.Title == "" {
+		doc.Title = http.StatusText(e.StatusCode)
+	}
+
+	paramsKey := h.problemParamsKey
+	if paramsKey == "" {
+		paramsKey = defaultProblemParamsKey
+	}
+
+	if paramsKey != defaultProblemParamsKey {
+		if slices.Contains(problemMembers, paramsKey) {
+			return nil, fmt.Errorf("details member '%s' collides with a problem details member", paramsKey)
+		}
+		doc.Details = nil
+	}
+
+	if _, ok := e.Extension[paramsKey]; ok {
+		return nil, fmt.Errorf("extension member '%s' collides with a problem details member", paramsKey)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Details == nil && len(e.Details) > 0 {
+		if b, err = appendMember(b, paramsKey, e.Details); err != nil {
+			return nil, err
+		}
+	}
+
+	return appendExtension(b, e.Extension, problemMembers, "problem details")
+}
+
+// appendExtension appends the members of ext to the JSON object obj, after its other members
+// and sorted so that bodies are the same on every run. Members colliding with the members
+// of the format, named in errors, are rejected.
+func appendExtension(obj []byte, ext map[string]any, members []string, format string) ([]byte, error) {
+	if len(ext) == 0 {
+		return obj, nil
+	}
+
+	keys := make([]string, 0, len(ext))
+	for k := range ext {
+		if slices.Contains(members, k) {
+			return nil, fmt.Errorf("extension member '%s' collides with a %s member", k, format)
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var err error
+	for _, k := range keys {
+		if obj, err = appendMember(obj, k, ext[k]); err != nil {
+			return nil, fmt.Errorf("could not marshal extension member '%s': %w", k, err)
+		}
+	}
+	return obj, nil
+}
+
+// appendMember appends the key member with the value v to the JSON object obj,
+// which must have members already.
+func appendMember(obj []byte, key string, v any) ([]byte, error) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj = append(obj[:len(obj)-1], ',')
+	obj = append(obj, k...)
+	obj = append(obj, ':')
+	obj = append(obj, value...)
+	return append(obj, '}'), nil
+}
+
+// resolveProblemURIs resolves the relative type and instance URI references of e
+// against the URL of r, when writing problem details documents.
+func (h *Handler) resolveProblemURIs(r *http.Request, e RESTErr) RESTErr {
+	if !h.problemFormat(e) || (e.Type == "" && e.Instance == "") {
+		return e
+	}
+
+	base := requestURL(r)
+
+	typ, typeChanged := resolveReference(base, e.Type)
+	instance, instanceChanged := resolveReference(base, e.Instance)
+
+	if !typeChanged && !instanceChanged {
+		return e
+	}
+
+	e.Type = typ
+	e.Instance = instance
+	e.json = nil
+	return e
+}
+
+// requestURL returns the absolute URL of r.
+func requestURL(r *http.Request) *url.URL {
+	if r.URL.IsAbs() {
+		return r.URL
+	}
+
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return &u
+}
+
+// resolveReference resolves the URI reference ref against base,
+// reporting whether it was relative.
+func resolveReference(base *url.URL, ref string) (string, bool) {
+	if ref == "" {
+		return ref, false
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref, false
+	}
+	return base.ResolveReference(u).String(), true
+}
@@ -0,0 +1,292 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWithProblemDetails(t *testing.T) {
+	t.Parallel()
+
+	errOutOfCredit := errors.New("out of credit")
+
+	// The out of credit example of RFC 9457, section 3, without its extension members.
+	errorMap := map[error]RESTErr{
+		errOutOfCredit: {
+			StatusCode: http.StatusForbidden,
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Message:    "Your current balance is 30, but that costs 50.",
+			Instance:   "/account/12345/msgs/abc",
+		},
+	}
+
+	handler, err := NewHandler(logger, errorMap, WithProblemDetails())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "specification example",
+			givenErr:           errOutOfCredit,
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody: `{
+				"type": "https://example.com/probs/out-of-credit",
+				"title": "You do not have enough credit.",
+				"status": 403,
+				"detail": "Your current balance is 30, but that costs 50.",
+				"instance": "https://example.com/account/12345/msgs/abc"
+			}`,
+		},
+		{
+			name:               "default type and title",
+			givenErr:           RESTErr{StatusCode: http.StatusNotFound, Message: "user not found"},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}`,
+		},
+		{
+			name: "relative type",
+			givenErr: RESTErr{
+				StatusCode: http.StatusConflict,
+				Message:    "email taken",
+				Type:       "/probs/conflict",
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"type":"https://example.com/probs/conflict","title":"Conflict","status":409,"detail":"email taken"}`,
+		},
+		{
+			name: "extension members",
+			givenErr: RESTErr{
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "invalid form",
+				Code:       42,
+				Details:    []Detail{{Field: "email", Message: "required"}},
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedBody: `{
+				"type": "about:blank",
+				"title": "Unprocessable Entity",
+				"status": 422,
+				"detail": "invalid form",
+				"code": 42,
+				"details": [{"field": "email", "message": "required"}]
+			}`,
+		},
+		{
+			name:               "unmapped error",
+			givenErr:           errors.New("foo err"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"something went wrong"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "https://example.com/account/12345/msgs", nil)
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, req, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, "application/problem+json", writer.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestHandleWithProblemDetailsStatusMember(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	// The status member matches the status code of the response even when it is rewritten.
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: errFoo.Error()},
+	},
+		WithProblemDetails(),
+		WithStatusCodeRewriter(func(statusCode int) int {
+			if statusCode == http.StatusNotFound {
+				return http.StatusGone
+			}
+			return statusCode
+		}),
+		WithStatusFromHeader("X-Upstream-Status"),
+	)
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+	handler.Handle(context.TODO(), writer, errFoo)
+
+	assert.Equal(t, http.StatusGone, writer.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Gone","status":410,"detail":"foo err"}`, writer.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Upstream-Status", "503")
+
+	writer = httptest.NewRecorder()
+	handler.HandleRequest(writer, req, errFoo)
+
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"foo err"}`, writer.Body.String())
+}
+
+func TestHandleWithProblemDetailsParamsKey(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithProblemDetails(), WithProblemDetailsParamsKey("invalid-params"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name: "with details",
+			givenErr: RESTErr{
+				StatusCode: http.StatusBadRequest,
+				Message:    "invalid form",
+				Details:    []Detail{{Field: "age", Message: "must be a positive integer"}},
+			},
+			expectedBody: `{
+				"type": "about:blank",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "invalid form",
+				"invalid-params": [{"field": "age", "message": "must be a positive integer"}]
+			}`,
+		},
+		{
+			name:         "without details",
+			givenErr:     RESTErr{StatusCode: http.StatusNotFound, Message: "user not found"},
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestNewHandlerWithCollidingProblemDetailsParamsKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHandler(logger, map[error]RESTErr{}, WithProblemDetails(), WithProblemDetailsParamsKey("status"))
+	assert.ErrorContains(t, err, "details member 'status' collides with a problem details member")
+}
+
+func TestHandleWithProblemDetailsExtension(t *testing.T) {
+	t.Parallel()
+
+	errOutOfCredit := errors.New("out of credit")
+
+	// The out of credit example of RFC 9457, section 3.
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errOutOfCredit: {
+			StatusCode: http.StatusForbidden,
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Message:    "Your current balance is 30, but that costs 50.",
+			Instance:   "/account/12345/msgs/abc",
+			Extension: map[string]any{
+				"balance":  30,
+				"accounts": []string{"/account/12345", "/account/67890"},
+			},
+		},
+	}, WithProblemDetails())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenErr           error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "specification example",
+			givenErr:           errOutOfCredit,
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody: `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",` +
+				`"status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc",` +
+				`"accounts":["/account/12345","/account/67890"],"balance":30}`,
+		},
+		{
+			name: "colliding extension member",
+			givenErr: RESTErr{
+				StatusCode: http.StatusForbidden,
+				Message:    "forbidden",
+				Extension:  map[string]any{"status": "denied"},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"something went wrong"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+			handler.Handle(context.TODO(), writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestNewHandlerWithCollidingProblemDetailsExtension(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenOpts []Option
+		givenKey  string
+	}{
+		{
+			name:      "standard member",
+			givenOpts: []Option{WithProblemDetails()},
+			givenKey:  "title",
+		},
+		{
+			name:      "details member",
+			givenOpts: []Option{WithProblemDetails(), WithProblemDetailsParamsKey("invalid-params")},
+			givenKey:  "invalid-params",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewHandler(logger, map[error]RESTErr{
+				errors.New("foo err"): {
+					StatusCode: http.StatusForbidden,
+					Message:    "forbidden",
+					Extension:  map[string]any{tc.givenKey: "foo"},
+				},
+			}, tc.givenOpts...)
+
+			assert.ErrorContains(t, err, "extension member '"+tc.givenKey+"' collides with a problem details member")
+		})
+	}
+}
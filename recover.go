@@ -0,0 +1,52 @@
+package resterr
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// WithPanicMapper is an option to set a function mapping values recovered by Recover to REST errors,
+// so that code can panic with a value such as a custom HTTPPanic{Status: 403} deep in a call stack
+// and have it result in a clean response. Values it does not map result in internal server errors.
+func WithPanicMapper(fn func(recovered any) (RESTErr, bool)) Option {
+	return func(h *Handler) {
+		h.panicMapperFn = fn
+	}
+}
+
+// Recover recovers from a panic and writes the REST error the recovered value maps to with
+// the panic mapper. Otherwise, the value and stack trace are logged and the value is handled
+// like in Handle: recovered errors are looked up in the error map, and other values, wrapped
+// in an error, result in the internal server error, with the hooks such as the error reporter
+// and metrics hooks called either way.
+// It must be deferred directly, as in defer h.Recover(ctx, w), and does nothing without a panic.
+// The http.ErrAbortHandler sentinel panic is not recovered, so that net/http aborts the response.
+func (h *Handler) Recover(ctx context.Context, w Writer) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	if h.panicMapperFn != nil {
+		if e, ok := h.panicMapperFn(recovered); ok {
+			h.logger.InfoContext(ctx, "Recovered from mapped panic.", slog.String("panic", h.redact(fmt.Sprint(recovered))))
+			h.handle(ctx, w, e)
+			return
+		}
+	}
+
+	h.logger.ErrorContext(ctx, "Recovered from panic.", slog.String("panic", h.redact(fmt.Sprint(recovered))), slog.String("stack", string(debug.Stack())))
+
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("panic: %v", recovered)
+	}
+	h.handle(ctx, w, err)
+}
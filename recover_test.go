@@ -0,0 +1,239 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type httpPanic struct {
+	Status int
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	panicMapper := func(recovered any) (RESTErr, bool) {
+		p, ok := recovered.(httpPanic)
+		if !ok {
+			return RESTErr{}, false
+		}
+		return RESTErr{StatusCode: p.Status, Message: http.StatusText(p.Status)}, true
+	}
+
+	errNotFound := errors.New("not found")
+
+	errorMap := map[error]RESTErr{
+		errNotFound: {StatusCode: http.StatusNotFound, Message: errNotFound.Error()},
+	}
+
+	testCases := []struct {
+		name               string
+		givenOpts          []Option
+		givenPanic         any
+		expectedStatusCode int
+		expectedBody       string
+		expectedStack      bool
+	}{
+		{
+			name:               "mapped panic",
+			givenOpts:          []Option{WithPanicMapper(panicMapper)},
+			givenPanic:         httpPanic{Status: http.StatusForbidden},
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"status-code":403,"message":"Forbidden"}`,
+		},
+		{
+			name:               "unmapped panic",
+			givenOpts:          []Option{WithPanicMapper(panicMapper)},
+			givenPanic:         errors.New("nil map"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status-code":500,"message":"something went wrong"}`,
+			expectedStack:      true,
+		},
+		{
+			name:               "mapped error panic",
+			givenPanic:         fmt.Errorf("load: %w", errNotFound),
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"status-code":404,"message":"not found"}`,
+			expectedStack:      true,
+		},
+		{
+			name:               "panic without mapper",
+			givenPanic:         httpPanic{Status: http.StatusForbidden},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status-code":500,"message":"something went wrong"}`,
+			expectedStack:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), errorMap, tc.givenOpts...)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			func() {
+				defer handler.Recover(context.TODO(), writer)
+				panic(tc.givenPanic)
+			}()
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+			assert.Equal(t, tc.expectedStack, strings.Contains(logData.String(), "resterr-handler.stack="))
+		})
+	}
+}
+
+func TestRecoverWithHooks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		givenPanic any
+	}{
+		{
+			name:       "error",
+			givenPanic: errors.New("nil map"),
+		},
+		{
+			name:       "other value",
+			givenPanic: "index out of range",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				reported []error
+				observed []int
+			)
+
+			handler, err := NewHandler(logger, map[error]RESTErr{},
+				WithErrorReporter(func(ctx context.Context, err error, restErr RESTErr) {
+					reported = append(reported, err)
+				}),
+				WithMetricsHook(func(ctx context.Context, restErr RESTErr) {
+					observed = append(observed, restErr.StatusCode)
+				}),
+				WithErrorID(func() string { return "abc123" }),
+			)
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			func() {
+				defer handler.Recover(context.TODO(), writer)
+				panic(tc.givenPanic)
+			}()
+
+			require.Len(t, reported, 1)
+			assert.Contains(t, reported[0].Error(), fmt.Sprint(tc.givenPanic))
+			assert.Equal(t, []int{http.StatusInternalServerError}, observed)
+			assert.JSONEq(t, `{"status-code":500,"message":"something went wrong","error-id":"abc123"}`, writer.Body.String())
+		})
+	}
+}
+
+func TestRecoverWithLogRedactor(t *testing.T) {
+	t.Parallel()
+
+	panicMapper := func(recovered any) (RESTErr, bool) {
+		s, ok := recovered.(string)
+		if !ok || !strings.HasPrefix(s, "forbidden") {
+			return RESTErr{}, false
+		}
+		return RESTErr{StatusCode: http.StatusForbidden, Message: http.StatusText(http.StatusForbidden)}, true
+	}
+
+	testCases := []struct {
+		name       string
+		givenPanic any
+	}{
+		{
+			name:       "mapped panic",
+			givenPanic: "forbidden with Bearer eyJhbGciOi.eyJzdWIi.SflKxw",
+		},
+		{
+			name:       "unmapped panic",
+			givenPanic: "token Bearer eyJhbGciOi.eyJzdWIi.SflKxw expired",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logData strings.Builder
+
+			logWriter := mockLogWriter{
+				writeFunc: func(p []byte) (n int, err error) {
+					return logData.Write(p)
+				},
+			}
+
+			handler, err := NewHandler(slog.New(slog.NewTextHandler(&logWriter, nil)), map[error]RESTErr{},
+				WithPanicMapper(panicMapper),
+				WithLogRedactor([]*regexp.Regexp{regexp.MustCompile(`Bearer [\w.-]+`)}),
+			)
+			require.NoError(t, err)
+
+			func() {
+				defer handler.Recover(context.TODO(), httptest.NewRecorder())
+				panic(tc.givenPanic)
+			}()
+
+			assert.Contains(t, logData.String(), "resterr-handler.panic=")
+			assert.Contains(t, logData.String(), "***")
+			assert.NotContains(t, logData.String(), "eyJhbGciOi")
+		})
+	}
+}
+
+func TestRecoverWithoutPanic(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	func() {
+		defer handler.Recover(context.TODO(), writer)
+	}()
+
+	assert.Empty(t, writer.Body.String())
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		defer handler.Recover(context.TODO(), httptest.NewRecorder())
+		panic(http.ErrAbortHandler)
+	})
+}
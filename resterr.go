@@ -1,8 +1,15 @@
 package resterr
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"net/http"
+	"reflect"
+	"slices"
+	"time"
 )
 
 var internalErr = RESTErr{
@@ -10,12 +17,128 @@ var internalErr = RESTErr{
 	Message:    "something went wrong",
 }
 
+// standardErrors maps common standard library errors to REST errors.
+// They are added to the error map by the WithStandardErrors option.
+var standardErrors = map[error]RESTErr{
+	io.ErrUnexpectedEOF: {
+		StatusCode: http.StatusBadRequest,
+		Message:    "malformed request body",
+	},
+	io.EOF: {
+		StatusCode: http.StatusBadRequest,
+		Message:    "empty request body",
+	},
+}
+
+// Detail is an individual problem behind a REST error.
+// Field is optional and names the offending input, such as a form field.
+type Detail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Severities of REST errors, telling clients how to present them.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// DefaultSeverity returns the severity for a status code: SeverityError for server errors,
+// SeverityWarning for client errors and SeverityInfo otherwise.
+func DefaultSeverity(statusCode int) string {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return SeverityError
+	case statusCode >= http.StatusBadRequest:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// StatusCoder is implemented by errors that know the HTTP status code they should result in.
+// Unmapped errors implementing it are handled with the fallback REST error instead of
+// resulting in internal server errors.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// WithStatus wraps err with statusCode for one-off statuses that do not deserve a mapping.
+// Handlers resolve the returned error to a REST error with statusCode and the message of err,
+// before looking it up in the error map, and log err as the original error.
+// It returns nil when err is nil.
+func WithStatus(err error, statusCode int) error {
+	if err == nil {
+		return nil
+	}
+	return &statusError{err: err, statusCode: statusCode}
+}
+
+type statusError struct {
+	err        error
+	statusCode int
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+func (e *statusError) Unwrap() error { return e.err }
+
+// Coder is implemented by errors carrying an application-specific error code,
+// which handlers configured with WithCodeMapper map to REST errors.
+type Coder interface {
+	Code() int
+}
+
 // RESTErr represents a RESTful error.
 // The json field is used to pre-marshal the error into JSON format.
+// LastModified is optional and only used by HandleRequest to answer conditional requests.
+// Headers are set on the response, overriding headers set by the handler.
+// LogAttrs are static attributes attached to the log line whenever the error is handled.
+// Translations hold the message by locale and are used by handlers configured with WithLocaleFromContext.
+// MessagesByEnv hold the message by environment and are used by handlers configured with WithEnvironment.
+// Code is an optional application-specific error code.
+// ErrorID is set by handlers configured with WithErrorID.
+// Severity is a hint for clients on how to present the error, such as SeverityWarning.
+// Details list the individual problems behind the error, such as invalid fields.
+// Their number is sent in the X-Error-Count header of the responses with details in their body.
+// Type, Title and Instance are the members of problem details documents written by handlers
+// configured with WithProblemDetails, and are not part of the default format.
+// The entries of Extension are written as top-level members after the others, in the default
+// format and in problem details documents, such as the balance of a payment problem or the
+// grpc_code set by the grpcerr module, and must not collide with the other members.
+// Custom formats, such as the one of WithMinimalFormat, leave them out.
+// The localized field is used to pre-marshal the translations, and the prepared field
+// marks errors from the error map, which were already validated and had their status code rewritten.
+// The debug field holds the debug information of handlers configured with WithDebugFromContext,
+// the deprecation field the note of mappings deprecated with DeprecateMapping, and the format field
+// the format selected by the request context with WithFormatFromContext, if any.
+// The detailsTruncated field marks the summaries written by WithSummaryOnLargeResponse,
+// and the originalStatus field holds the status code before it was rewritten or overridden, if it was.
 type RESTErr struct {
-	StatusCode int    `json:"status-code"`
-	Message    string `json:"message"`
-	json       []byte `json:"-"`
+	StatusCode       int               `json:"status-code"`
+	Message          string            `json:"message"`
+	Code             int               `json:"code,omitempty"`
+	ErrorID          string            `json:"error-id,omitempty"`
+	Severity         string            `json:"severity,omitempty"`
+	Details          []Detail          `json:"details,omitempty"`
+	Type             string            `json:"-"`
+	Title            string            `json:"-"`
+	Instance         string            `json:"-"`
+	Extension        map[string]any    `json:"-"`
+	LastModified     time.Time         `json:"-"`
+	Headers          http.Header       `json:"-"`
+	LogAttrs         []slog.Attr       `json:"-"`
+	Translations     map[string]string `json:"-"`
+	MessagesByEnv    map[string]string `json:"-"`
+	json             []byte            `json:"-"`
+	localized        map[string][]byte `json:"-"`
+	prepared         bool              `json:"-"`
+	debug            *debugInfo        `json:"-"`
+	deprecation      string            `json:"-"`
+	format           Format            `json:"-"`
+	detailsTruncated bool              `json:"-"`
+	originalStatus   int               `json:"-"`
 }
 
 // Error implements the error interface.
@@ -25,3 +148,40 @@ func (r RESTErr) Error() string {
 		r.StatusCode, r.Message, string(r.json),
 	)
 }
+
+// Equal reports whether r and other have the same status code, message, codes, severity, details,
+// type, title, instance and extension members, which make the body of the response. The error ID,
+// which is generated, the fields that are not serialized, such as Headers, and the unexported
+// fields, such as the pre-marshaled JSON, are ignored.
+func (r RESTErr) Equal(other RESTErr) bool {
+	return r.StatusCode == other.StatusCode &&
+		r.Message == other.Message &&
+		r.Code == other.Code &&
+		r.Severity == other.Severity &&
+		slices.Equal(r.Details, other.Details) &&
+		r.Type == other.Type &&
+		r.Title == other.Title &&
+		r.Instance == other.Instance &&
+		maps.EqualFunc(r.Extension, other.Extension, func(v, w any) bool {
+			return reflect.DeepEqual(v, w)
+		})
+}
+
+// Response returns the HTTP status code and JSON body the error serializes to.
+// Errors from the handler's error map return their pre-marshaled JSON,
+// which reflects the handler's configured format.
+func (r RESTErr) Response() (int, []byte, error) {
+	if r.json != nil {
+		return r.StatusCode, r.json, nil
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not marshal REST error: %w", err)
+	}
+
+	if b, err = appendExtension(b, r.Extension, jsonMembers, "REST error"); err != nil {
+		return 0, nil, fmt.Errorf("could not marshal REST error: %w", err)
+	}
+	return r.StatusCode, b, nil
+}
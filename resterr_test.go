@@ -1,9 +1,11 @@
 package resterr
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRESTErr_Error(t *testing.T) {
@@ -18,3 +20,205 @@ func TestRESTErr_Error(t *testing.T) {
 
 	assert.Equal(t, expected, observed)
 }
+
+func TestRESTErr_Response(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without pre-marshaled JSON", func(t *testing.T) {
+		t.Parallel()
+
+		statusCode, body, err := RESTErr{
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		}.Response()
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, statusCode)
+		assert.JSONEq(t, `{"status-code":404,"message":"not found"}`, string(body))
+	})
+
+	t.Run("with pre-marshaled JSON", func(t *testing.T) {
+		t.Parallel()
+
+		statusCode, body, err := RESTErr{
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+			json:       []byte(`{"cached":true}`),
+		}.Response()
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, statusCode)
+		assert.Equal(t, `{"cached":true}`, string(body))
+	})
+
+	t.Run("with extension members", func(t *testing.T) {
+		t.Parallel()
+
+		_, body, err := RESTErr{
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+			Extension:  map[string]any{"grpc_code": 5},
+		}.Response()
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"status-code":404,"message":"not found","grpc_code":5}`, string(body))
+	})
+}
+
+func TestRESTErr_Equal(t *testing.T) {
+	t.Parallel()
+
+	e := RESTErr{
+		StatusCode: http.StatusUnprocessableEntity,
+		Message:    "invalid form",
+		Code:       42,
+		Details:    []Detail{{Field: "email", Message: "required"}},
+	}
+
+	testCases := []struct {
+		name     string
+		given    RESTErr
+		expected bool
+	}{
+		{
+			name:     "same error",
+			given:    e,
+			expected: true,
+		},
+		{
+			name: "different pre-marshaled JSON",
+			given: func() RESTErr {
+				other := e
+				other.json = []byte(`{"cached":true}`)
+				return other
+			}(),
+			expected: true,
+		},
+		{
+			name: "different error ID and headers",
+			given: func() RESTErr {
+				other := e
+				other.ErrorID = "abc"
+				other.Headers = http.Header{"Retry-After": []string{"1"}}
+				return other
+			}(),
+			expected: true,
+		},
+		{
+			name: "different status code",
+			given: func() RESTErr {
+				other := e
+				other.StatusCode = http.StatusBadRequest
+				return other
+			}(),
+		},
+		{
+			name: "different message",
+			given: func() RESTErr {
+				other := e
+				other.Message = "bad form"
+				return other
+			}(),
+		},
+		{
+			name: "different code",
+			given: func() RESTErr {
+				other := e
+				other.Code = 43
+				return other
+			}(),
+		},
+		{
+			name: "different details",
+			given: func() RESTErr {
+				other := e
+				other.Details = []Detail{{Field: "name", Message: "required"}}
+				return other
+			}(),
+		},
+		{
+			name: "different type",
+			given: func() RESTErr {
+				other := e
+				other.Type = "https://example.com/probs/invalid-form"
+				return other
+			}(),
+		},
+		{
+			name: "different title",
+			given: func() RESTErr {
+				other := e
+				other.Title = "Invalid form"
+				return other
+			}(),
+		},
+		{
+			name: "different extension",
+			given: func() RESTErr {
+				other := e
+				other.Extension = map[string]any{"balance": 30}
+				return other
+			}(),
+		},
+		{
+			name: "empty extension",
+			given: func() RESTErr {
+				other := e
+				other.Extension = map[string]any{}
+				return other
+			}(),
+			expected: true,
+		},
+		{
+			name: "different instance",
+			given: func() RESTErr {
+				other := e
+				other.Instance = "/forms/42"
+				return other
+			}(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, e.Equal(tc.given))
+			assert.Equal(t, tc.expected, tc.given.Equal(e))
+		})
+	}
+}
+
+func TestDefaultSeverity(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		givenStatusCode int
+		expected        string
+	}{
+		{
+			name:            "server error",
+			givenStatusCode: http.StatusServiceUnavailable,
+			expected:        SeverityError,
+		},
+		{
+			name:            "client error",
+			givenStatusCode: http.StatusNotFound,
+			expected:        SeverityWarning,
+		},
+		{
+			name:            "other status",
+			givenStatusCode: http.StatusAccepted,
+			expected:        SeverityInfo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, DefaultSeverity(tc.givenStatusCode))
+		})
+	}
+}
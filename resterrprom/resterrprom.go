@@ -0,0 +1,79 @@
+// Package resterrprom exposes the errors handled by resterr handlers as Prometheus metrics.
+//
+// It lives in its own module so that the core package does not depend on the Prometheus client.
+// A Collector counts the handled errors in resterr_handled_total, by status code and category:
+//
+//	collector := resterrprom.NewCollector()
+//	prometheus.MustRegister(collector)
+//
+//	handler, err := resterr.NewHandler(logger, errMap, collector.Option())
+package resterrprom
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/alesr/resterr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Categories of handled errors, by status code class.
+const (
+	CategoryClient = "client"
+	CategoryServer = "server"
+	CategoryOther  = "other"
+)
+
+// Category returns the category of statusCode: CategoryServer for server errors,
+// CategoryClient for client errors and CategoryOther otherwise.
+func Category(statusCode int) string {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return CategoryServer
+	case statusCode >= http.StatusBadRequest:
+		return CategoryClient
+	default:
+		return CategoryOther
+	}
+}
+
+// Collector is a prometheus.Collector counting the errors handled by resterr handlers
+// in resterr_handled_total, labeled with their status code and category.
+type Collector struct {
+	handled *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector, to register with a Prometheus registry.
+func NewCollector() *Collector {
+	return &Collector{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "resterr_handled_total",
+			Help: "Number of errors handled, by status code and category.",
+		}, []string{"status", "category"}),
+	}
+}
+
+// Option returns the option to pass to resterr.NewHandler for the handler to be counted by c.
+// Several handlers can share a collector.
+func (c *Collector) Option() resterr.Option {
+	return resterr.WithMetricsHook(c.Hook)
+}
+
+// Hook counts restErr. It is the metrics hook set by Option, exposed for advanced users
+// who compose it with their own, such as to skip some errors.
+func (c *Collector) Hook(_ context.Context, restErr resterr.RESTErr) {
+	c.handled.WithLabelValues(strconv.Itoa(restErr.StatusCode), Category(restErr.StatusCode)).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.handled.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.handled.Collect(ch)
+}
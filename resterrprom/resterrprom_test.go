@@ -0,0 +1,85 @@
+package resterrprom
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alesr/resterr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func TestCategory(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    int
+		expected string
+	}{
+		{
+			name:     "server error",
+			given:    http.StatusBadGateway,
+			expected: CategoryServer,
+		},
+		{
+			name:     "client error",
+			given:    http.StatusNotFound,
+			expected: CategoryClient,
+		},
+		{
+			name:     "other",
+			given:    http.StatusAccepted,
+			expected: CategoryOther,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, Category(tc.given))
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	collector := NewCollector()
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	handler, err := resterr.NewHandler(logger, map[error]resterr.RESTErr{
+		errFoo: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+	}, collector.Option())
+	require.NoError(t, err)
+
+	handler.Handle(context.TODO(), httptest.NewRecorder(), errFoo)
+	handler.Handle(context.TODO(), httptest.NewRecorder(), errFoo)
+	handler.Handle(context.TODO(), httptest.NewRecorder(), errors.New("bar err"))
+
+	expected := `
+# HELP resterr_handled_total Number of errors handled, by status code and category.
+# TYPE resterr_handled_total counter
+resterr_handled_total{category="client",status="404"} 2
+resterr_handled_total{category="server",status="500"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "resterr_handled_total"))
+}
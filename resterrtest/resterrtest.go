@@ -0,0 +1,31 @@
+// Package resterrtest provides test helpers asserting invariants of resterr handlers.
+package resterrtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/alesr/resterr"
+)
+
+// AssertNeverSuccess asserts that h resolves each of errs to a REST error with an error status
+// code, 400 or above, which catches mappings such as RESTErr{StatusCode: 200} registered by
+// mistake. It is meant for strictly-error handlers and reports every offending error by name,
+// returning whether the assertion held.
+func AssertNeverSuccess(t testing.TB, h *resterr.Handler, errs ...error) bool {
+	t.Helper()
+
+	ok := true
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if statusCode := h.Resolve(context.Background(), err).StatusCode; statusCode < http.StatusBadRequest {
+			t.Errorf("error %q resolves to non-error status %d %s", err, statusCode, http.StatusText(statusCode))
+			ok = false
+		}
+	}
+	return ok
+}
@@ -0,0 +1,79 @@
+package resterrtest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/alesr/resterr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+// recorder is a testing.TB recording errors instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNeverSuccess(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("not found err")
+	errAccepted := errors.New("accepted err")
+
+	handler, err := resterr.NewHandler(logger, map[error]resterr.RESTErr{
+		errNotFound: {
+			StatusCode: http.StatusNotFound,
+			Message:    "not found",
+		},
+		errAccepted: {
+			StatusCode: http.StatusOK,
+			Message:    "accepted",
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		givenErrs      []error
+		expectedOK     bool
+		expectedErrors []string
+	}{
+		{
+			name:       "error statuses",
+			givenErrs:  []error{errNotFound, fmt.Errorf("wrapped: %w", errNotFound), errors.New("unmapped err"), nil},
+			expectedOK: true,
+		},
+		{
+			name:       "success status",
+			givenErrs:  []error{errNotFound, fmt.Errorf("could not accept: %w", errAccepted)},
+			expectedOK: false,
+			expectedErrors: []string{
+				`error "could not accept: accepted err" resolves to non-error status 200 OK`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recorder{TB: t}
+
+			assert.Equal(t, tc.expectedOK, AssertNeverSuccess(rec, handler, tc.givenErrs...))
+			assert.Equal(t, tc.expectedErrors, rec.errors)
+		})
+	}
+}
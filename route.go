@@ -0,0 +1,109 @@
+package resterr
+
+import (
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Route maps errors to REST errors for the requests matching a method and path pattern,
+// for API gateways where the same backend error means different statuses on different endpoints.
+// An empty Method or "*" matches any method. Path is matched with path.Match, so that "*"
+// matches within a path segment, except for a trailing "*" which matches any rest of the path:
+// "/users/*/orders" matches "/users/42/orders", and "/users/*" matches "/users/42/orders" too.
+type Route struct {
+	Method   string
+	Path     string
+	ErrorMap map[error]RESTErr
+}
+
+// WithRoutes is an option to set the routes whose error maps HandleRequest matches errors
+// against first, in the order given, before falling back to the handler's error map.
+// The REST errors of routes are validated and pre-marshaled like the ones of the error map.
+// Within a route, the mapped error whose message sorts first wins when several match.
+func WithRoutes(routes ...Route) Option {
+	return func(h *Handler) {
+		h.routes = append(h.routes, routes...)
+	}
+}
+
+// resolveRequest resolves err like resolve, looking it up in the variants for r and
+// in the error maps of the routes matching r first, with the status code of the request
+// header set with WithStatusFromHeader.
+func (h *Handler) resolveRequest(r *http.Request, err error) RESTErr {
+	ctx := r.Context()
+
+	if h.onHandleFn != nil {
+		h.onHandleFn(ctx, err)
+	}
+
+	restErr, res := h.matchRequest(r, err)
+	restErr = h.statusFromHeader(r, restErr)
+	return h.conclude(ctx, err, restErr, res)
+}
+
+// matchRequest looks up the REST error for err like match, in the variants for r and
+// in the error maps of the routes matching r first.
+func (h *Handler) matchRequest(r *http.Request, err error) (RESTErr, resolution) {
+	if re, res, ok := h.variant(r, err); ok {
+		return re, res
+	}
+
+	if len(h.routes) == 0 {
+		return h.match(r.Context(), err)
+	}
+
+	candidates := h.candidates(err)
+	for i, route := range h.routes {
+		if !route.matches(r) {
+			continue
+		}
+
+		for j, m := range h.routeMappings[i] {
+			if isAny(candidates, m.err) {
+				return m.restErr, mapped("Handling route mapped error.", slog.String("route", route.name()), j)
+			}
+		}
+	}
+	return h.match(r.Context(), err)
+}
+
+// name returns the method and path patterns of the route, such as "GET /users/*",
+// with "*" for any method.
+func (route Route) name() string {
+	method := route.Method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + route.Path
+}
+
+// matches reports whether r matches the method and path patterns of the route.
+func (route Route) matches(r *http.Request) bool {
+	if route.Method != "" && route.Method != "*" && !strings.EqualFold(route.Method, r.Method) {
+		return false
+	}
+	return matchPath(route.Path, r.URL.Path)
+}
+
+// matchPath reports whether p matches pattern, with a trailing "*" matching any rest of the path.
+func matchPath(pattern, p string) bool {
+	if !strings.HasSuffix(pattern, "*") {
+		ok, err := path.Match(pattern, p)
+		return err == nil && ok
+	}
+
+	// The trailing "*" matches the rest of the path by matching any leading part
+	// of the path that ends at a segment boundary.
+	for i := 0; i <= len(p); i++ {
+		if i < len(p) && p[i] != '/' {
+			continue
+		}
+
+		if ok, err := path.Match(pattern, p[:i]); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
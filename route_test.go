@@ -0,0 +1,195 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRequestWithRoutes(t *testing.T) {
+	t.Parallel()
+
+	errUpstream := errors.New("upstream err")
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errUpstream: {StatusCode: http.StatusBadGateway, Message: "bad gateway"},
+	}, WithRoutes(
+		Route{
+			Method:   http.MethodGet,
+			Path:     "/users/*",
+			ErrorMap: map[error]RESTErr{errUpstream: {StatusCode: http.StatusNotFound, Message: "user not found"}},
+		},
+		Route{
+			Method:   "*",
+			Path:     "/orders/*/items",
+			ErrorMap: map[error]RESTErr{errUpstream: {StatusCode: http.StatusConflict, Message: "order is closed"}},
+		},
+		Route{
+			Path:     "/orders/*",
+			ErrorMap: map[error]RESTErr{errFoo: {StatusCode: http.StatusGone, Message: "order deleted"}},
+		},
+	))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		givenMethod        string
+		givenPath          string
+		givenErr           error
+		expectedStatusCode int
+		expectedMessage    string
+	}{
+		{
+			name:               "prefix route",
+			givenMethod:        http.MethodGet,
+			givenPath:          "/users/42/profile",
+			givenErr:           errUpstream,
+			expectedStatusCode: http.StatusNotFound,
+			expectedMessage:    "user not found",
+		},
+		{
+			name:               "wrapped error",
+			givenMethod:        http.MethodGet,
+			givenPath:          "/users/42",
+			givenErr:           fmt.Errorf("call users: %w", errUpstream),
+			expectedStatusCode: http.StatusNotFound,
+			expectedMessage:    "user not found",
+		},
+		{
+			name:               "method mismatch falls back to error map",
+			givenMethod:        http.MethodDelete,
+			givenPath:          "/users/42",
+			givenErr:           errUpstream,
+			expectedStatusCode: http.StatusBadGateway,
+			expectedMessage:    "bad gateway",
+		},
+		{
+			name:               "glob route",
+			givenMethod:        http.MethodPost,
+			givenPath:          "/orders/7/items",
+			givenErr:           errUpstream,
+			expectedStatusCode: http.StatusConflict,
+			expectedMessage:    "order is closed",
+		},
+		{
+			name:               "glob mismatch falls back to error map",
+			givenMethod:        http.MethodPost,
+			givenPath:          "/orders/7/payments",
+			givenErr:           errUpstream,
+			expectedStatusCode: http.StatusBadGateway,
+			expectedMessage:    "bad gateway",
+		},
+		{
+			name:               "error not in first matching route",
+			givenMethod:        http.MethodPost,
+			givenPath:          "/orders/7/items",
+			givenErr:           errFoo,
+			expectedStatusCode: http.StatusGone,
+			expectedMessage:    "order deleted",
+		},
+		{
+			name:               "unmapped error",
+			givenMethod:        http.MethodGet,
+			givenPath:          "/users/42",
+			givenErr:           errors.New("bar err"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedMessage:    "something went wrong",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := httptest.NewRecorder()
+
+			handler.HandleRequest(writer, httptest.NewRequest(tc.givenMethod, tc.givenPath, nil), tc.givenErr)
+
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"status-code":%d,"message":%q}`, tc.expectedStatusCode, tc.expectedMessage), writer.Body.String())
+		})
+	}
+}
+
+func TestHandleRequestCallsOnHandleOnce(t *testing.T) {
+	t.Parallel()
+
+	errUpstream := errors.New("upstream err")
+
+	var handled int
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errUpstream: {StatusCode: http.StatusBadGateway, Message: "bad gateway"},
+	}, WithRoutes(Route{
+		Path:     "/users/*",
+		ErrorMap: map[error]RESTErr{errUpstream: {StatusCode: http.StatusNotFound, Message: "user not found"}},
+	}), WithVariants(func(r *http.Request) string {
+		return r.Header.Get("X-Client-Capabilities")
+	}, map[error]map[string]RESTErr{
+		errUpstream: {"rich": {StatusCode: http.StatusServiceUnavailable, Message: "users unavailable"}},
+	}), WithOnHandle(func(ctx context.Context, err error) {
+		handled++
+	}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		givenPath         string
+		givenCapabilities string
+		givenErr          error
+	}{
+		{name: "variant", givenPath: "/users/42", givenCapabilities: "rich", givenErr: errUpstream},
+		{name: "route", givenPath: "/users/42", givenErr: errUpstream},
+		{name: "error map", givenPath: "/orders/42", givenErr: errUpstream},
+		{name: "unmapped", givenPath: "/users/42", givenErr: errors.New("foo err")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handled = 0
+
+			req := httptest.NewRequest(http.MethodGet, tc.givenPath, nil)
+			req.Header.Set("X-Client-Capabilities", tc.givenCapabilities)
+
+			handler.HandleRequest(httptest.NewRecorder(), req, tc.givenErr)
+
+			assert.Equal(t, 1, handled)
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "/users", path: "/users", expected: true},
+		{pattern: "/users", path: "/users/42", expected: false},
+		{pattern: "/users/*", path: "/users/42", expected: true},
+		{pattern: "/users/*", path: "/users/42/orders", expected: true},
+		{pattern: "/users/*", path: "/users", expected: false},
+		{pattern: "/users/*/orders", path: "/users/42/orders", expected: true},
+		{pattern: "/users/*/orders", path: "/users/42/orders/7", expected: false},
+		{pattern: "/users/*/orders/*", path: "/users/42/orders/7/items", expected: true},
+		{pattern: "/users/[", path: "/users/[", expected: false},
+		{pattern: "*", path: "/anything", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, matchPath(tc.pattern, tc.path))
+		})
+	}
+}
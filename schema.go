@@ -0,0 +1,76 @@
+package resterr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// Schema holds constraints on REST errors, for teams generating their error map from
+// configuration files. Zero fields impose no constraint.
+type Schema struct {
+	// StatusCodes are the allowed status codes.
+	StatusCodes []int
+	// MinStatusCode and MaxStatusCode bound the status codes, inclusively.
+	MinStatusCode int
+	MaxStatusCode int
+	// MaxMessageLength is the maximum length of the message, in characters.
+	MaxMessageLength int
+	// RequireMessage rejects empty messages.
+	RequireMessage bool
+	// RequireCode rejects REST errors without an application-specific code.
+	RequireCode bool
+	// RequireSeverity rejects REST errors without a severity.
+	RequireSeverity bool
+	// RequireDetailFields rejects details without a field.
+	RequireDetailFields bool
+}
+
+// ValidateSchema returns a validation function, for WithValidationFn, rejecting REST errors
+// that violate the constraints of schema. All the violations of a REST error are reported,
+// joined in a single error. The function can be composed with other validation functions,
+// such as by calling it first in a custom one.
+func ValidateSchema(schema Schema) func(restErr RESTErr) error {
+	return func(restErr RESTErr) error {
+		var errs []error
+
+		if len(schema.StatusCodes) > 0 && !slices.Contains(schema.StatusCodes, restErr.StatusCode) {
+			errs = append(errs, fmt.Errorf("status code %d is not allowed", restErr.StatusCode))
+		}
+
+		if schema.MinStatusCode != 0 && restErr.StatusCode < schema.MinStatusCode {
+			errs = append(errs, fmt.Errorf("status code %d is below %d", restErr.StatusCode, schema.MinStatusCode))
+		}
+
+		if schema.MaxStatusCode != 0 && restErr.StatusCode > schema.MaxStatusCode {
+			errs = append(errs, fmt.Errorf("status code %d is above %d", restErr.StatusCode, schema.MaxStatusCode))
+		}
+
+		if schema.RequireMessage && restErr.Message == "" {
+			errs = append(errs, errors.New("message is required"))
+		}
+
+		if n := utf8.RuneCountInString(restErr.Message); schema.MaxMessageLength != 0 && n > schema.MaxMessageLength {
+			errs = append(errs, fmt.Errorf("message is %d characters long, more than %d", n, schema.MaxMessageLength))
+		}
+
+		if schema.RequireCode && restErr.Code == 0 {
+			errs = append(errs, errors.New("code is required"))
+		}
+
+		if schema.RequireSeverity && restErr.Severity == "" {
+			errs = append(errs, errors.New("severity is required"))
+		}
+
+		if schema.RequireDetailFields {
+			for i, d := range restErr.Details {
+				if d.Field == "" {
+					errs = append(errs, fmt.Errorf("detail %d has no field", i))
+				}
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
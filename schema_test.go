@@ -0,0 +1,101 @@
+package resterr
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenSchema Schema
+		given       RESTErr
+		expectedErr string
+	}{
+		{
+			name:        "empty schema",
+			givenSchema: Schema{},
+			given:       RESTErr{},
+		},
+		{
+			name: "valid REST error",
+			givenSchema: Schema{
+				StatusCodes:         []int{http.StatusBadRequest, http.StatusNotFound},
+				MaxMessageLength:    9,
+				RequireMessage:      true,
+				RequireCode:         true,
+				RequireSeverity:     true,
+				RequireDetailFields: true,
+			},
+			given: RESTErr{
+				StatusCode: http.StatusNotFound,
+				Message:    "not found",
+				Code:       7,
+				Severity:   SeverityWarning,
+				Details:    []Detail{{Field: "id", Message: "unknown"}},
+			},
+		},
+		{
+			name:        "status code not allowed",
+			givenSchema: Schema{StatusCodes: []int{http.StatusBadRequest}},
+			given:       RESTErr{StatusCode: http.StatusNotFound},
+			expectedErr: "status code 404 is not allowed",
+		},
+		{
+			name:        "status code out of bounds",
+			givenSchema: Schema{MinStatusCode: 400, MaxStatusCode: 499},
+			given:       RESTErr{StatusCode: http.StatusInternalServerError},
+			expectedErr: "status code 500 is above 499",
+		},
+		{
+			name:        "message too long in characters",
+			givenSchema: Schema{MaxMessageLength: 4},
+			given:       RESTErr{Message: "échec"},
+			expectedErr: "message is 5 characters long, more than 4",
+		},
+		{
+			name:        "message at the limit in characters",
+			givenSchema: Schema{MaxMessageLength: 5},
+			given:       RESTErr{Message: "échec"},
+		},
+		{
+			name:        "every violation",
+			givenSchema: Schema{MinStatusCode: 400, RequireMessage: true, RequireCode: true, RequireSeverity: true, RequireDetailFields: true},
+			given:       RESTErr{StatusCode: http.StatusOK, Details: []Detail{{Message: "foo"}}},
+			expectedErr: "status code 200 is below 400\nmessage is required\ncode is required\nseverity is required\ndetail 0 has no field",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateSchema(tc.givenSchema)(tc.given)
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestNewHandlerWithValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHandler(logger, map[error]RESTErr{
+		errors.New("foo err"): {
+			StatusCode: http.StatusNotFound,
+		},
+	}, WithValidationFn(ValidateSchema(Schema{RequireMessage: true})))
+	require.Error(t, err)
+
+	assert.ErrorContains(t, err, "message is required")
+}
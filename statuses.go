@@ -0,0 +1,113 @@
+package resterr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Errors signaling conditions that are rarely handled but defined by the HTTP specification,
+// mapped by handlers configured with WithExtendedStatuses.
+var (
+	// ErrHeaderTooLarge signals request header fields too large to be processed, such as
+	// when an upstream gateway rejects them.
+	ErrHeaderTooLarge = errors.New("request header fields too large")
+	// ErrRateLimited signals a client sending too many requests.
+	ErrRateLimited = errors.New("too many requests")
+	// ErrLegalBlock signals a resource unavailable for legal reasons.
+	ErrLegalBlock = errors.New("unavailable for legal reasons")
+)
+
+// extendedErrors maps the errors of the extended HTTP statuses to REST errors.
+var extendedErrors = map[error]RESTErr{
+	ErrHeaderTooLarge: HeaderTooLargeErr(),
+	ErrRateLimited: {
+		StatusCode: http.StatusTooManyRequests,
+		Message:    "too many requests",
+	},
+	ErrLegalBlock: {
+		StatusCode: http.StatusUnavailableForLegalReasons,
+		Message:    "unavailable for legal reasons",
+	},
+}
+
+// HeaderTooLargeErr returns a 431 Request Header Fields Too Large REST error, defined by RFC 6585,
+// for requests whose header fields are too large, rather than an internal server error.
+func HeaderTooLargeErr() RESTErr {
+	return RESTErr{
+		StatusCode: http.StatusRequestHeaderFieldsTooLarge,
+		Message:    "request header fields too large",
+	}
+}
+
+// LegalBlockErr returns a 451 Unavailable For Legal Reasons REST error, identifying the
+// entity that caused the block with a Link header, as recommended by RFC 7725.
+func LegalBlockErr(blockingAuthorityURL string) RESTErr {
+	return RESTErr{
+		StatusCode: http.StatusUnavailableForLegalReasons,
+		Message:    "unavailable for legal reasons",
+		Headers: http.Header{
+			"Link": []string{fmt.Sprintf(`<%s>; rel="blocked-by"`, blockingAuthorityURL)},
+		},
+	}
+}
+
+// AcceptedResponse returns a 202 Accepted REST error acknowledging a request that is processed
+// asynchronously, with statusURL in the Location header for clients to poll the outcome.
+// Handlers write it like any other REST error, with the same serialization.
+func AcceptedResponse(statusURL string) RESTErr {
+	return RESTErr{
+		StatusCode: http.StatusAccepted,
+		Message:    "request accepted for processing",
+		Headers: http.Header{
+			"Location": []string{statusURL},
+		},
+	}
+}
+
+// MethodNotAllowedErr returns a 405 Method Not Allowed REST error with the Allow header
+// listing the allowed methods of the target resource, which RFC 9110 requires.
+// Methods are uppercased and deduplicated, keeping their order, and an empty list means
+// the resource allows no method.
+func MethodNotAllowedErr(allowed ...string) RESTErr {
+	methods := make([]string, 0, len(allowed))
+	for _, m := range allowed {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m != "" && !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+
+	return RESTErr{
+		StatusCode: http.StatusMethodNotAllowed,
+		Message:    "method not allowed",
+		Headers: http.Header{
+			"Allow": []string{strings.Join(methods, ", ")},
+		},
+	}
+}
+
+// QueryParamErr returns a 400 Bad Request REST error for a query parameter that failed to parse,
+// naming param in the message and detailing why with err. The errors of the strconv package
+// are reduced to their reason, such as "invalid syntax", so that the parsing function
+// and the input are not echoed back.
+func QueryParamErr(param string, err error) RESTErr {
+	reason := "invalid value"
+
+	var numErr *strconv.NumError
+	switch {
+	case errors.As(err, &numErr):
+		reason = numErr.Err.Error()
+	case err != nil:
+		reason = err.Error()
+	}
+
+	return RESTErr{
+		StatusCode: http.StatusBadRequest,
+		Message:    fmt.Sprintf("invalid query parameter '%s'", param),
+		Details:    []Detail{{Field: param, Message: reason}},
+	}
+}
@@ -0,0 +1,147 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderTooLargeErr(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, HeaderTooLargeErr())
+
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, writer.Code)
+	assert.JSONEq(t, `{"status-code":431,"message":"request header fields too large"}`, writer.Body.String())
+}
+
+func TestLegalBlockErr(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{})
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, LegalBlockErr("https://authority.example.org"))
+
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, writer.Code)
+	assert.Equal(t, `<https://authority.example.org>; rel="blocked-by"`, writer.Header().Get("Link"))
+}
+
+func TestAcceptedResponse(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewHandler(logger, map[error]RESTErr{}, WithDefaultSeverity())
+	require.NoError(t, err)
+
+	writer := httptest.NewRecorder()
+
+	handler.Handle(context.TODO(), writer, AcceptedResponse("/jobs/42"))
+
+	assert.Equal(t, http.StatusAccepted, writer.Code)
+	assert.Equal(t, "/jobs/42", writer.Header().Get("Location"))
+	assert.JSONEq(t, `{"status-code":202,"message":"request accepted for processing","severity":"info"}`, writer.Body.String())
+}
+
+func TestMethodNotAllowedErr(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenAllowed  []string
+		expectedAllow string
+	}{
+		{
+			name:          "allowed methods",
+			givenAllowed:  []string{http.MethodGet, http.MethodHead},
+			expectedAllow: "GET, HEAD",
+		},
+		{
+			name:          "normalized methods",
+			givenAllowed:  []string{"get", " POST ", "GET", ""},
+			expectedAllow: "GET, POST",
+		},
+		{
+			name:          "no allowed method",
+			expectedAllow: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, map[error]RESTErr{})
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, MethodNotAllowedErr(tc.givenAllowed...))
+
+			assert.Equal(t, http.StatusMethodNotAllowed, writer.Code)
+			assert.Equal(t, []string{tc.expectedAllow}, writer.Header().Values("Allow"))
+			assert.JSONEq(t, `{"status-code":405,"message":"method not allowed"}`, writer.Body.String())
+		})
+	}
+}
+
+func TestQueryParamErr(t *testing.T) {
+	t.Parallel()
+
+	_, errSyntax := strconv.Atoi("abc")
+	_, errRange := strconv.ParseInt("99999999999999999999", 10, 64)
+
+	testCases := []struct {
+		name         string
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "strconv syntax error",
+			givenErr:     errSyntax,
+			expectedBody: `{"status-code":400,"message":"invalid query parameter 'limit'","details":[{"field":"limit","message":"invalid syntax"}]}`,
+		},
+		{
+			name:         "strconv range error",
+			givenErr:     errRange,
+			expectedBody: `{"status-code":400,"message":"invalid query parameter 'limit'","details":[{"field":"limit","message":"value out of range"}]}`,
+		},
+		{
+			name:         "other error",
+			givenErr:     errors.New("must be positive"),
+			expectedBody: `{"status-code":400,"message":"invalid query parameter 'limit'","details":[{"field":"limit","message":"must be positive"}]}`,
+		},
+		{
+			name:         "nil error",
+			expectedBody: `{"status-code":400,"message":"invalid query parameter 'limit'","details":[{"field":"limit","message":"invalid value"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, map[error]RESTErr{})
+			require.NoError(t, err)
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(context.TODO(), writer, QueryParamErr("limit", tc.givenErr))
+
+			assert.Equal(t, http.StatusBadRequest, writer.Code)
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package resterr
+
+import (
+	"context"
+	"strings"
+)
+
+// WithTraceContextHeader is an option to set the W3C Trace Context traceparent header on error
+// responses, so that clients can correlate failed requests with traces. The traceparent value
+// is extracted from the context by extractFn, such as from the span of a tracing library,
+// which keeps the library out of this package. Responses only get the header when the value
+// is a valid traceparent, as in "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func WithTraceContextHeader(extractFn func(ctx context.Context) string) Option {
+	return func(h *Handler) {
+		h.traceparentFn = extractFn
+	}
+}
+
+// traceparent returns the valid traceparent value in ctx, if any.
+func (h *Handler) traceparent(ctx context.Context) (string, bool) {
+	if h.traceparentFn == nil {
+		return "", false
+	}
+
+	v := h.traceparentFn(ctx)
+	if !validTraceparent(v) {
+		return "", false
+	}
+	return v, true
+}
+
+// validTraceparent reports whether v is a valid version 00 traceparent header value:
+// a trace ID of 32 and a parent ID of 16 lowercase hex digits, neither all zeros,
+// and trace flags of 2 hex digits.
+func validTraceparent(v string) bool {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return false
+	}
+
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	return validHexID(traceID, 32) && validHexID(parentID, 16) && lowerHex(flags) && len(flags) == 2
+}
+
+func validHexID(id string, length int) bool {
+	return len(id) == length && lowerHex(id) && strings.Trim(id, "0") != ""
+}
+
+func lowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
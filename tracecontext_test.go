@@ -0,0 +1,94 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceparentCtxKey struct{}
+
+func TestHandleWithTraceContextHeader(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errFoo: {StatusCode: http.StatusNotFound, Message: errFoo.Error()},
+	}, WithTraceContextHeader(func(ctx context.Context) string {
+		v, _ := ctx.Value(traceparentCtxKey{}).(string)
+		return v
+	}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name                string
+		givenTraceparent    string
+		givenErr            error
+		expectedTraceparent string
+	}{
+		{
+			name:                "valid trace context",
+			givenTraceparent:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			givenErr:            errFoo,
+			expectedTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:                "valid trace context on internal error",
+			givenTraceparent:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			givenErr:            errors.New("bar err"),
+			expectedTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		{
+			name:     "no trace context",
+			givenErr: errFoo,
+		},
+		{
+			name:             "all zeros trace ID",
+			givenTraceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			givenErr:         errFoo,
+		},
+		{
+			name:             "all zeros parent ID",
+			givenTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			givenErr:         errFoo,
+		},
+		{
+			name:             "unknown version",
+			givenTraceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			givenErr:         errFoo,
+		},
+		{
+			name:             "uppercase hex",
+			givenTraceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			givenErr:         errFoo,
+		},
+		{
+			name:             "short trace ID",
+			givenTraceparent: "00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+			givenErr:         errFoo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.TODO()
+			if tc.givenTraceparent != "" {
+				ctx = context.WithValue(ctx, traceparentCtxKey{}, tc.givenTraceparent)
+			}
+
+			writer := httptest.NewRecorder()
+
+			handler.Handle(ctx, writer, tc.givenErr)
+
+			assert.Equal(t, tc.expectedTraceparent, writer.Header().Get("Traceparent"))
+		})
+	}
+}
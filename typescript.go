@@ -0,0 +1,297 @@
+package resterr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// tsIdentifier matches keys that can be used unquoted as TypeScript property names.
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsExtensionMember is the member of the sample REST error finding whether the format
+// writes Extension members.
+const tsExtensionMember = "extension-sample"
+
+// TypeScriptDefinition returns a TypeScript interface describing the JSON body of the
+// error responses written by the handler. The definition is derived from the handler's
+// serialization configuration: members that may be omitted from the body are optional.
+// Formats writing the Extension members of REST errors, whose names are only known at
+// run time, get an index signature of unknown members.
+func (h *Handler) TypeScriptDefinition() string {
+	def, err := h.typeScriptDefinition()
+	if err != nil {
+		h.logger.Error("Failed to generate TypeScript definition.", slog.String(h.errKey, err.Error()))
+		return ""
+	}
+	return def
+}
+
+func (h *Handler) typeScriptDefinition() (string, error) {
+	// A zero REST error only yields the top-level members that are always present,
+	// while a fully populated one yields every member and a sample of its type.
+	// Nested members that are always present are found in a sample populated
+	// with zero values, which still has non-empty slices and maps.
+	_, required, err := h.response(RESTErr{})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal required members: %w", err)
+	}
+
+	// The Extension members are written at the top level under their own names,
+	// so that the member populate gives the map would be taken for a member of the body.
+	var full RESTErr
+	populate(reflect.ValueOf(&full).Elem(), true)
+	full.Extension = nil
+
+	_, fullJSON, err := h.response(full)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal sample: %w", err)
+	}
+
+	var zero RESTErr
+	populate(reflect.ValueOf(&zero).Elem(), false)
+	zero.Extension = nil
+
+	_, zeroJSON, err := h.response(zero)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal zero sample: %w", err)
+	}
+
+	requiredFields, err := objectFields(required)
+	if err != nil {
+		return "", fmt.Errorf("could not parse required members: %w", err)
+	}
+
+	fields, err := objectFields(fullJSON)
+	if err != nil {
+		return "", fmt.Errorf("could not parse sample: %w", err)
+	}
+
+	zeroFields, err := objectFields(zeroJSON)
+	if err != nil {
+		return "", fmt.Errorf("could not parse zero sample: %w", err)
+	}
+
+	_, extendedJSON, err := h.response(RESTErr{Extension: map[string]any{tsExtensionMember: true}})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal extension sample: %w", err)
+	}
+
+	extendedFields, err := objectFields(extendedJSON)
+	if err != nil {
+		return "", fmt.Errorf("could not parse extension sample: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("export interface RESTErr {\n")
+
+	for _, f := range fields {
+		typ, err := tsType(f.value, fieldValue(zeroFields, f.name))
+		if err != nil {
+			return "", fmt.Errorf("could not infer type of '%s': %w", f.name, err)
+		}
+
+		optional := "?"
+		if req := fieldValue(requiredFields, f.name); req != nil {
+			optional = ""
+
+			// Members serialized as null when empty, such as details with EmptyDetailsNull.
+			if string(bytes.TrimSpace(req)) == "null" && typ != "unknown" {
+				typ += " | null"
+			}
+		}
+		fmt.Fprintf(&sb, "  %s%s: %s;\n", tsKey(f.name), optional, typ)
+	}
+
+	if fieldValue(extendedFields, tsExtensionMember) != nil {
+		sb.WriteString("  [member: string]: unknown;\n")
+	}
+
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
+
+type jsonField struct {
+	name  string
+	value json.RawMessage
+}
+
+// objectFields returns the members of a JSON object in the order they appear.
+func objectFields(b []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected JSON object, got '%v'", tok)
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{name: tok.(string), value: value})
+	}
+	return fields, nil
+}
+
+// fieldValue returns the value of the named member, or nil when absent.
+func fieldValue(fields []jsonField, name string) json.RawMessage {
+	for _, f := range fields {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return nil
+}
+
+// tsType infers the TypeScript type of a JSON value. Members of objects are optional
+// unless present in zero, the same value with zero values, which may be nil.
+func tsType(value, zero json.RawMessage) (string, error) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return "", fmt.Errorf("empty JSON value")
+	}
+
+	switch value[0] {
+	case '{':
+		fields, err := objectFields(value)
+		if err != nil {
+			return "", err
+		}
+
+		var zeroFields []jsonField
+		if len(zero) > 0 && zero[0] == '{' {
+			if zeroFields, err = objectFields(zero); err != nil {
+				return "", err
+			}
+		}
+
+		members := make([]string, 0, len(fields))
+		for _, f := range fields {
+			zeroValue := fieldValue(zeroFields, f.name)
+
+			typ, err := tsType(f.value, zeroValue)
+			if err != nil {
+				return "", err
+			}
+
+			optional := "?"
+			if zeroValue != nil {
+				optional = ""
+			}
+			members = append(members, fmt.Sprintf("%s%s: %s", tsKey(f.name), optional, typ))
+		}
+		return "{ " + strings.Join(members, "; ") + " }", nil
+	case '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(value, &elems); err != nil {
+			return "", err
+		}
+
+		if len(elems) == 0 {
+			return "unknown[]", nil
+		}
+
+		var zeroElems []json.RawMessage
+		if len(zero) > 0 && zero[0] == '[' {
+			if err := json.Unmarshal(zero, &zeroElems); err != nil {
+				return "", err
+			}
+		}
+
+		var zeroElem json.RawMessage
+		if len(zeroElems) > 0 {
+			zeroElem = zeroElems[0]
+		}
+
+		typ, err := tsType(elems[0], zeroElem)
+		if err != nil {
+			return "", err
+		}
+		return typ + "[]", nil
+	case '"':
+		return "string", nil
+	case 't', 'f':
+		return "boolean", nil
+	case 'n':
+		return "unknown", nil
+	default:
+		return "number", nil
+	}
+}
+
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// populate fills every exported field reachable from v, giving slices, maps and pointers
+// a single element. Other values are set to a non-zero value when leaves is true,
+// so that members declared with omitempty are serialized, or left to their zero value.
+func populate(v reflect.Value, leaves bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				populate(f, leaves)
+			}
+		}
+		return
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		populate(v.Elem(), leaves)
+		return
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		populate(v.Index(0), leaves)
+		return
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+
+		key := reflect.New(v.Type().Key()).Elem()
+		key.SetString("key")
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+		populate(elem, leaves)
+
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, elem)
+		return
+	}
+
+	if !leaves {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("sample")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	}
+}
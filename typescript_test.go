@@ -0,0 +1,170 @@
+package resterr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeScriptDefinition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenOpts []Option
+		expected  string
+	}{
+		{
+			name: "default format",
+			expected: `export interface RESTErr {
+  "status-code": number;
+  message: string;
+  code?: number;
+  "error-id"?: string;
+  severity?: string;
+  details?: { field?: string; message: string }[];
+  [member: string]: unknown;
+}
+`,
+		},
+		{
+			name:      "null empty details",
+			givenOpts: []Option{WithEmptyDetailsBehavior(EmptyDetailsNull)},
+			expected: `export interface RESTErr {
+  "status-code": number;
+  message: string;
+  code?: number;
+  "error-id"?: string;
+  severity?: string;
+  details: { field?: string; message: string }[] | null;
+  [member: string]: unknown;
+}
+`,
+		},
+		{
+			name:      "empty array details",
+			givenOpts: []Option{WithEmptyDetailsBehavior(EmptyDetailsArray)},
+			expected: `export interface RESTErr {
+  "status-code": number;
+  message: string;
+  code?: number;
+  "error-id"?: string;
+  severity?: string;
+  details: { field?: string; message: string }[];
+  [member: string]: unknown;
+}
+`,
+		},
+		{
+			name:      "status as string",
+			givenOpts: []Option{WithStatusAsString()},
+			expected: `export interface RESTErr {
+  "status-code": string;
+  message: string;
+  code?: number;
+  "error-id"?: string;
+  severity?: string;
+  details?: { field?: string; message: string }[];
+  [member: string]: unknown;
+}
+`,
+		},
+		{
+			name:      "problem details",
+			givenOpts: []Option{WithProblemDetails()},
+			expected: `export interface RESTErr {
+  type: string;
+  title?: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  code?: number;
+  "error-id"?: string;
+  severity?: string;
+  details?: { field?: string; message: string }[];
+  [member: string]: unknown;
+}
+`,
+		},
+		{
+			name:      "minimal format",
+			givenOpts: []Option{WithMinimalFormat("")},
+			expected: `export interface RESTErr {
+  m: string;
+}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(logger, map[error]RESTErr{}, tc.givenOpts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, handler.TypeScriptDefinition())
+		})
+	}
+}
+
+func TestTSType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		given     string
+		givenZero string
+		expected  string
+	}{
+		{
+			name:     "number",
+			given:    `1`,
+			expected: "number",
+		},
+		{
+			name:     "string",
+			given:    `"foo"`,
+			expected: "string",
+		},
+		{
+			name:     "boolean",
+			given:    `false`,
+			expected: "boolean",
+		},
+		{
+			name:     "null",
+			given:    `null`,
+			expected: "unknown",
+		},
+		{
+			name:     "empty array",
+			given:    `[]`,
+			expected: "unknown[]",
+		},
+		{
+			name:      "array of objects",
+			given:     `[{"field":"foo","max-length":1}]`,
+			givenZero: `[{"max-length":0}]`,
+			expected:  `{ field?: string; "max-length": number }[]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var zero json.RawMessage
+			if tc.givenZero != "" {
+				zero = json.RawMessage(tc.givenZero)
+			}
+
+			observed, err := tsType(json.RawMessage(tc.given), zero)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, observed)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package resterr
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// WithVariants is an option to serve different REST errors for the same error depending on
+// the client, such as detailed validation errors to rich clients and a simple message to legacy
+// ones. selectorFn tags the request with the variant the client calls for, typically read from
+// a header declaring its capabilities, and variants map errors to their REST errors by tag.
+// HandleRequest matches errors against the variants of the request's tag first, before the routes
+// and the handler's error map, which serve requests without a tag or a variant for it.
+// The REST errors of variants are validated and pre-marshaled like the ones of the error map.
+// When an error matches variants of several mapped errors for its tag, the variant of the
+// mapped error whose message sorts first is served.
+func WithVariants(selectorFn func(r *http.Request) string, variants map[error]map[string]RESTErr) Option {
+	return func(h *Handler) {
+		h.variantFn = selectorFn
+
+		// Variants are matched by tag, so they are kept by tag.
+		h.variants = make(map[string]map[error]RESTErr)
+		for k, byTag := range variants {
+			for tag, e := range byTag {
+				if h.variants[tag] == nil {
+					h.variants[tag] = make(map[error]RESTErr)
+				}
+				h.variants[tag][k] = e
+			}
+		}
+	}
+}
+
+// variant returns the REST error of the variant of err for the tag of r, if any,
+// and how err was resolved to it.
+func (h *Handler) variant(r *http.Request, err error) (RESTErr, resolution, bool) {
+	if h.variantFn == nil {
+		return RESTErr{}, resolution{}, false
+	}
+
+	tag := h.variantFn(r)
+	if tag == "" {
+		return RESTErr{}, resolution{}, false
+	}
+
+	candidates := h.candidates(err)
+	for i, m := range h.variantMappings[tag] {
+		if isAny(candidates, m.err) {
+			return m.restErr, mapped("Handling variant mapped error.", slog.String("variant", tag), i), true
+		}
+	}
+	return RESTErr{}, resolution{}, false
+}
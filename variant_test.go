@@ -0,0 +1,109 @@
+package resterr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRequestWithVariants(t *testing.T) {
+	t.Parallel()
+
+	errInvalid := errors.New("invalid err")
+
+	selector := func(r *http.Request) string {
+		return r.Header.Get("X-Client-Capabilities")
+	}
+
+	handler, err := NewHandler(logger, map[error]RESTErr{
+		errInvalid: {
+			StatusCode: http.StatusBadRequest,
+			Message:    "invalid request",
+		},
+	}, WithVariants(selector, map[error]map[string]RESTErr{
+		errInvalid: {
+			"rich": {
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "invalid form",
+				Details:    []Detail{{Field: "email", Message: "required"}},
+			},
+		},
+	}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		givenTag     string
+		givenErr     error
+		expectedBody string
+	}{
+		{
+			name:         "rich client",
+			givenTag:     "rich",
+			givenErr:     fmt.Errorf("could not sign up: %w", errInvalid),
+			expectedBody: `{"status-code":422,"message":"invalid form","details":[{"field":"email","message":"required"}]}`,
+		},
+		{
+			name:         "legacy client",
+			givenErr:     errInvalid,
+			expectedBody: `{"status-code":400,"message":"invalid request"}`,
+		},
+		{
+			name:         "client without a variant",
+			givenTag:     "compact",
+			givenErr:     errInvalid,
+			expectedBody: `{"status-code":400,"message":"invalid request"}`,
+		},
+		{
+			name:         "unmapped error",
+			givenTag:     "rich",
+			givenErr:     errors.New("foo err"),
+			expectedBody: `{"status-code":500,"message":"something went wrong"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+			if tc.givenTag != "" {
+				req.Header.Set("X-Client-Capabilities", tc.givenTag)
+			}
+
+			writer := httptest.NewRecorder()
+			handler.HandleRequest(writer, req, tc.givenErr)
+
+			assert.JSONEq(t, tc.expectedBody, writer.Body.String())
+		})
+	}
+}
+
+func TestNewHandlerWithInvalidVariant(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHandler(logger, map[error]RESTErr{}, WithValidationFn(ValidateSchema(Schema{RequireMessage: true})),
+		WithVariants(func(*http.Request) string { return "" }, map[error]map[string]RESTErr{
+			errors.New("foo err"): {"rich": {StatusCode: http.StatusBadRequest}},
+		}),
+	)
+
+	assert.ErrorContains(t, err, "could not prepare variant 'rich' of 'foo err'")
+}
+
+func TestNewHandlerWithNilVariantError(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHandler(logger, map[error]RESTErr{},
+		WithVariants(func(*http.Request) string { return "" }, map[error]map[string]RESTErr{
+			nil: {"rich": {StatusCode: http.StatusBadRequest, Message: "bad request"}},
+		}),
+	)
+
+	assert.ErrorContains(t, err, "could not prepare variant 'rich'")
+}
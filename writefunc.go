@@ -0,0 +1,61 @@
+package resterr
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// WithWriteFunc is an option to deliver error responses with fn instead of the Writer,
+// for transports other than HTTP, such as WebSocket frames or a message queue. fn is called
+// once per handled error with the status code, headers and body the Writer would have received,
+// and Handle, HandleRequest and Recover then leave the Writer untouched, so that it can be nil.
+// Errors returned by fn are logged.
+func WithWriteFunc(fn func(ctx context.Context, statusCode int, headers http.Header, body []byte) error) Option {
+	return func(h *Handler) {
+		h.writeFn = fn
+	}
+}
+
+// transport returns the Writer to write a response to, and a function delivering it with the
+// write function when one is set. The delivery function must be called once the response is written.
+func (h *Handler) transport(ctx context.Context, w Writer) (Writer, func()) {
+	if h.writeFn == nil {
+		return w, func() {}
+	}
+
+	fw := &funcWriter{header: make(http.Header)}
+	return fw, func() {
+		statusCode := fw.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+
+		if err := h.writeFn(ctx, statusCode, fw.header, fw.body.Bytes()); err != nil {
+			h.logger.ErrorContext(ctx, "Failed to deliver error response.", slog.Int("status-code", statusCode), slog.String(h.errKey, err.Error()))
+		}
+	}
+}
+
+// funcWriter is a Writer recording the response delivered with the write function.
+type funcWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (fw *funcWriter) Header() http.Header { return fw.header }
+
+func (fw *funcWriter) WriteHeader(statusCode int) {
+	if fw.statusCode == 0 {
+		fw.statusCode = statusCode
+	}
+}
+
+func (fw *funcWriter) Write(b []byte) (int, error) {
+	if fw.statusCode == 0 {
+		fw.statusCode = http.StatusOK
+	}
+	return fw.body.Write(b)
+}
@@ -0,0 +1,114 @@
+package resterr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type delivery struct {
+	statusCode int
+	headers    http.Header
+	body       string
+}
+
+func TestHandleWithWriteFunc(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo err")
+
+	errMap := map[error]RESTErr{
+		errFoo: {
+			StatusCode: http.StatusTooManyRequests,
+			Message:    "slow down",
+			Headers:    http.Header{"Retry-After": []string{"30"}},
+		},
+	}
+
+	newHandler := func(t *testing.T, fnErr error) (*Handler, *[]delivery) {
+		var deliveries []delivery
+
+		handler, err := NewHandler(logger, errMap, WithNoStore(), WithWriteFunc(
+			func(_ context.Context, statusCode int, headers http.Header, body []byte) error {
+				deliveries = append(deliveries, delivery{statusCode: statusCode, headers: headers, body: string(body)})
+				return fnErr
+			},
+		))
+		require.NoError(t, err)
+
+		return handler, &deliveries
+	}
+
+	t.Run("handle", func(t *testing.T) {
+		t.Parallel()
+
+		handler, deliveries := newHandler(t, nil)
+
+		handler.Handle(context.TODO(), nil, errFoo)
+
+		require.Len(t, *deliveries, 1)
+
+		d := (*deliveries)[0]
+		assert.Equal(t, http.StatusTooManyRequests, d.statusCode)
+		assert.Equal(t, "application/json", d.headers.Get("Content-Type"))
+		assert.Equal(t, "no-store", d.headers.Get("Cache-Control"))
+		assert.Equal(t, "30", d.headers.Get("Retry-After"))
+		assert.JSONEq(t, `{"status-code":429,"message":"slow down"}`, d.body)
+	})
+
+	t.Run("handle request", func(t *testing.T) {
+		t.Parallel()
+
+		handler, deliveries := newHandler(t, nil)
+
+		writer := httptest.NewRecorder()
+		handler.HandleRequest(writer, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("bar err"))
+
+		require.Len(t, *deliveries, 1)
+		assert.Equal(t, http.StatusInternalServerError, (*deliveries)[0].statusCode)
+		assert.JSONEq(t, `{"status-code":500,"message":"something went wrong"}`, (*deliveries)[0].body)
+
+		assert.Empty(t, writer.Header())
+		assert.Empty(t, writer.Body.String())
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		t.Parallel()
+
+		handler, deliveries := newHandler(t, nil)
+
+		func() {
+			defer handler.Recover(context.TODO(), nil)
+			panic("boom")
+		}()
+
+		require.Len(t, *deliveries, 1)
+		assert.Equal(t, http.StatusInternalServerError, (*deliveries)[0].statusCode)
+	})
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+
+		handler, deliveries := newHandler(t, nil)
+
+		handler.Handle(context.TODO(), nil, nil)
+
+		assert.Empty(t, *deliveries)
+	})
+
+	t.Run("failed delivery", func(t *testing.T) {
+		t.Parallel()
+
+		handler, deliveries := newHandler(t, errors.New("queue closed"))
+
+		assert.NotPanics(t, func() {
+			handler.Handle(context.TODO(), nil, errFoo)
+		})
+		assert.Len(t, *deliveries, 1)
+	})
+}